@@ -3,19 +3,25 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/sitanshunandan/tardigo/internal/biomodel"
+	"github.com/sitanshunandan/tardigo/internal/chronotype"
 	"github.com/sitanshunandan/tardigo/internal/storage"
 )
 
 // Server struct to hold dependencies
 type Server struct {
-	repo *storage.TelemetryRepository
+	repo   *storage.TelemetryRepository
+	curves *biomodel.CurveCache // Sampled capacity per user, shared by every endpoint
 }
 
 func main() {
@@ -45,13 +51,30 @@ func main() {
 		defer repo.Close(ctx)
 	}
 
-	srv := &Server{repo: repo}
+	srv := &Server{repo: repo, curves: biomodel.NewCurveCache()}
 
 	// 2. Setup Routes
 	// GET: Status Check
 	http.HandleFunc("/capacity/now", srv.HandleGetCurrentCapacity)
+	// GET: Multi-day capacity forecast
+	http.HandleFunc("/capacity/forecast", srv.HandleGetForecast)
 	// POST: The Intelligence Engine (NEW)
 	http.HandleFunc("/schedule/optimize", srv.HandleOptimizeSchedule)
+	// POST: Log a sleep episode
+	http.HandleFunc("/sleep", srv.HandleLogSleep)
+	// GET: Predicted natural night and bedtime advice
+	http.HandleFunc("/sleep/advice", srv.HandleSleepAdvice)
+	// GET: Best nap before a block that needs to go well
+	http.HandleFunc("/sleep/nap", srv.HandleNapAdvice)
+	// GET: Latest safe time for a coffee
+	http.HandleFunc("/caffeine/advice", srv.HandleCaffeineAdvice)
+	// POST: Score a chronotype questionnaire and store the calibrated params
+	http.HandleFunc("/calibrate", srv.HandleCalibrate)
+	// POST: Observations the fitter learns from
+	http.HandleFunc("/alertness", srv.HandleReportAlertness)
+	http.HandleFunc("/telemetry/heart", srv.HandleReportHeart)
+	// POST: Fit FatigueRate, ChronotypeLag and weights to the user's own data
+	http.HandleFunc("/params/fit", srv.HandleFitParams)
 
 	// 3. Start Server
 	port := ":8080"
@@ -59,20 +82,26 @@ func main() {
 	log.Fatal(http.ListenAndServe(port, nil))
 }
 
-// HandleGetCurrentCapacity (GET) - Existing Logic
+// HandleGetCurrentCapacity (GET) - Latest capacity for ?user= (default user_001)
 func (s *Server) HandleGetCurrentCapacity(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	userID := "user_001"
+	userID := userOrDefault(r.URL.Query().Get("user"))
 	state, err := s.repo.GetLatestCapacity(r.Context(), userID)
 	if err != nil {
 		http.Error(w, "Biological signal lost: "+err.Error(), http.StatusNotFound)
 		return
 	}
 
+	// Debt comes from the sleep log rather than telemetry, so it reflects last night's entry right away
+	params, _ := s.bioParams(r.Context(), userID, "", time.Now())
+	now := localNow(params)
+	debt := params.SleepDebt(now)
+	peak := biomodel.BioState{SleepDebt: debt}.PeakCapacity()
+
 	var recommendation string
 	if state.TotalCapacity > 0.8 {
 		recommendation = "Deep Work / High Focus (Coding, Math)"
@@ -85,18 +114,50 @@ func (s *Server) HandleGetCurrentCapacity(w http.ResponseWriter, r *http.Request
 	response := map[string]interface{}{
 		"user":           userID,
 		"status":         "connected",
+		"time":           now.Format(time.RFC3339),
+		"time_zone":      now.Location().String(),
 		"capacity_score": state.TotalCapacity,
 		"components": map[string]float64{
 			"freshness": state.ProcessS,
 			"circadian": state.ProcessC,
 		},
-		"recommendation": recommendation,
+		"sleep_debt_hours": debt,
+		"peak_capacity":    peak,
+		"performance":      state.Performance(),
+		"recommendation":   recommendation,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// OptimizeRequest is the /schedule/optimize payload.
+// For backwards compatibility the endpoint also accepts a bare JSON array of tasks.
+type OptimizeRequest struct {
+	UserID string `json:"user_id"`
+	ModelSettings
+	biomodel.ScheduleOptions
+	Tasks []biomodel.Task `json:"tasks"`
+}
+
+// ModelSettings are the per-request overrides accepted wherever we build a user's model.
+type ModelSettings struct {
+	Model            string                     `json:"model"`              // "average" (default), "multiplicative", "alertness" or "weighted"
+	Weights          *biomodel.Weights          `json:"weights"`            // Only used by the weighted model
+	Itinerary        biomodel.Itinerary         `json:"itinerary"`          // Upcoming/recent trips across time zones
+	Light            biomodel.LightLog          `json:"light"`              // Light exposure; switches Process C to the light-driven oscillator
+	Caffeine         biomodel.CaffeineLog       `json:"caffeine"`           // Intake today (and planned), masks sleep pressure
+	CaffeineHalfLife float64                    `json:"caffeine_half_life"` // Hours; 0 uses the 5h default
+	Ultradian        biomodel.Ultradian         `json:"ultradian"`          // ~90 minute focus waves; off unless amplitude is set
+	Circadian        *biomodel.CircadianProfile `json:"circadian"`          // Shape of Process C, e.g. {"preset": "post_lunch_dip"}
+	TimeZone         string                     `json:"time_zone"`          // IANA zone, overrides the profile's, e.g. "Asia/Tokyo"
+
+	// Monte Carlo: sample FatigueRate and ChronotypeLag to get p10/p90 bands.
+	// The spread defaults to how well we know the user (see uncertainty).
+	MonteCarlo  bool                  `json:"monte_carlo"`
+	Uncertainty *biomodel.Uncertainty `json:"uncertainty"`
+}
+
 // HandleOptimizeSchedule (POST) - NEW Logic
 func (s *Server) HandleOptimizeSchedule(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -105,32 +166,761 @@ func (s *Server) HandleOptimizeSchedule(w http.ResponseWriter, r *http.Request)
 	}
 
 	// A. Parse the Incoming Tasks
-	var tasks []biomodel.Task
-	if err := json.NewDecoder(r.Body).Decode(&tasks); err != nil {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	var req OptimizeRequest
+	if len(raw) > 0 && raw[0] == '[' {
+		// Legacy payload: just the task list
+		if err := json.Unmarshal(raw, &req.Tasks); err != nil {
+			http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+	} else if err := json.Unmarshal(raw, &req); err != nil {
 		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
 		return
 	}
 
+	// Reject bad tasks and options before building anything.
+	// Task problems (e.g. a dependency cycle) come back as structured JSON naming the tasks.
+	if err := biomodel.ValidateTasks(req.Tasks); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if err := req.ScheduleOptions.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Pessimistic planning needs the sampled bands, with this user's spread
+	if req.Pessimistic || req.BandFloor != nil {
+		req.MonteCarlo = true
+	}
+
 	// B. Setup User Bio-Params
-	// In a real app, we would fetch these "Settings" from the DB.
-	// For now, we assume the user woke up at 7 AM today.
+	// Calibrated/fitted settings come from the user's profile, sleep from their log.
+	userID := userOrDefault(req.UserID)
 	now := time.Now()
-	wakeTime := time.Date(now.Year(), now.Month(), now.Day(), 7, 0, 0, 0, now.Location())
-
-	params := biomodel.BioParams{
-		WakeTime:      wakeTime,
-		ChronotypeLag: 0.0,
-		FatigueRate:   16.0,
+	// Load (and plan) sleep for the whole horizon, and never less than a day
+	horizon := 24 * time.Hour
+	if h := time.Duration(req.HorizonHours) * time.Hour; h > horizon {
+		horizon = h
+	}
+	model, err := s.userModel(r.Context(), userID, req.ModelSettings, now, now.Add(horizon))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	// C. Run the Algorithm
 	// We schedule starting from the current hour
-	schedule := biomodel.OptimizeSchedule(tasks, now, params)
+	schedule, err := biomodel.OptimizeSchedule(req.Tasks, now, model, req.ScheduleOptions)
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	// D. Return the Plan, flat (as before) and grouped per day
+	plan := biomodel.GroupByDay(schedule)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"algorithm":   "TardiGo-Greedy-v1",
+		"model":       model.Name(),
+		"time_zone":   localNow(model.Biology()).Location().String(),
+		"schedule":    schedule,
+		"days":        plan.Days,
+		"unscheduled": plan.Unscheduled,
+	})
+}
+
+// HandleGetForecast (GET) - Capacity for the next ?days=N days (default 3, max 7), optionally with
+// ?model=, a ?circadian= preset and ?bands=true for Monte Carlo p10/p90 bands
+func (s *Server) HandleGetForecast(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	days := 3
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 7 {
+			http.Error(w, "days must be an integer between 1 and 7", http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+
+	userID := userOrDefault(r.URL.Query().Get("user"))
+	now := time.Now().Truncate(time.Hour)
+	until := now.Add(time.Duration(days) * 24 * time.Hour)
+
+	settings := ModelSettings{Model: r.URL.Query().Get("model"), MonteCarlo: r.URL.Query().Get("bands") == "true"}
+	if preset := r.URL.Query().Get("circadian"); preset != "" {
+		settings.Circadian = &biomodel.CircadianProfile{Preset: preset}
+	}
+	model, err := s.userModel(r.Context(), userID, settings, now, until)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	// D. Return the Plan
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"algorithm": "TardiGo-Greedy-v1",
-		"schedule":  schedule,
+		"user":      userID,
+		"model":     model.Name(),
+		"time_zone": localNow(model.Biology()).Location().String(),
+		"forecast":  biomodel.Forecast(model, now, until, time.Hour),
 	})
 }
+
+// HandleLogSleep (POST) - Records a sleep episode: {"user_id": "...", "start": RFC3339, "end": RFC3339, "planned": bool, "nap": bool}
+func (s *Server) HandleLogSleep(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.repo == nil {
+		http.Error(w, "Sleep log unavailable in offline mode", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req struct {
+		UserID string `json:"user_id"`
+		biomodel.SleepEpisode
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+	ep := req.SleepEpisode
+	if err := (biomodel.SleepLog{ep}).Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := userOrDefault(req.UserID)
+	if err := s.repo.SaveSleepEpisode(r.Context(), userID, ep); err != nil {
+		http.Error(w, "Failed to save sleep episode: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.curves.Invalidate(userID)
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// HandleSleepAdvice (GET) - Predicted natural night, plus the latest bedtime for ?wake=RFC3339
+// (defaults to the next 07:00).
+func (s *Server) HandleSleepAdvice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := userOrDefault(r.URL.Query().Get("user"))
+	// Only past sleep matters here; the advice is about the nights we have not planned yet.
+	params, _ := s.bioParams(r.Context(), userID, "", time.Now())
+
+	// 07:00 on the user's clock, not the server's
+	now := localNow(params)
+	targetWake := time.Date(now.Year(), now.Month(), now.Day(), 7, 0, 0, 0, now.Location())
+	if !targetWake.After(now) {
+		targetWake = targetWake.AddDate(0, 0, 1)
+	}
+	if raw := r.URL.Query().Get("wake"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil || !t.After(now) {
+			http.Error(w, "wake must be a future RFC3339 time", http.StatusBadRequest)
+			return
+		}
+		targetWake = t.In(now.Location())
+	}
+
+	window, err := biomodel.PredictSleepWindow(params, now)
+	if err != nil {
+		http.Error(w, "Could not predict sleep: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	bedtime, achievable := biomodel.OptimalBedtime(params, now, targetWake)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user":            userID,
+		"predicted_sleep": window,
+		"predicted_hours": window.Duration().Hours(),
+		"target_wake":     targetWake,
+		"optimal_bedtime": bedtime,
+		"achievable":      achievable,
+	})
+}
+
+// HandleNapAdvice (GET) - Best nap between now and the block ?start=RFC3339&end=RFC3339, e.g. an
+// evening on-call window.
+func (s *Server) HandleNapAdvice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var block biomodel.TimeBlock
+	var err error
+	if block.Start, err = time.Parse(time.RFC3339, r.URL.Query().Get("start")); err != nil {
+		http.Error(w, "start must be an RFC3339 time", http.StatusBadRequest)
+		return
+	}
+	if block.End, err = time.Parse(time.RFC3339, r.URL.Query().Get("end")); err != nil {
+		http.Error(w, "end must be an RFC3339 time", http.StatusBadRequest)
+		return
+	}
+
+	userID := userOrDefault(r.URL.Query().Get("user"))
+	now := time.Now()
+	model, err := s.userModel(r.Context(), userID, ModelSettings{Model: r.URL.Query().Get("model")}, now, block.End)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	now = localNow(model.Biology())
+	block.Start, block.End = block.Start.In(now.Location()), block.End.In(now.Location())
+
+	plan, worthIt, err := biomodel.RecommendNap(model, now, block)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := map[string]interface{}{
+		"user":              userID,
+		"block":             block,
+		"recommended":       worthIt,
+		"baseline_capacity": plan.Baseline,
+	}
+	if worthIt {
+		response["nap"] = plan.Nap
+		response["nap_minutes"] = plan.Nap.End.Sub(plan.Nap.Start).Minutes()
+		response["block_capacity"] = plan.Capacity
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// CaffeineIntake is what the user has already had today, taken like ModelSettings' fields of the
+// same names: as a JSON body (POST), or on a GET as ?caffeine= holding the same JSON array and
+// ?caffeine_half_life=.
+type CaffeineIntake struct {
+	Caffeine         biomodel.CaffeineLog `json:"caffeine"`
+	CaffeineHalfLife float64              `json:"caffeine_half_life"`
+}
+
+// caffeineIntake reads and validates the intake from either place.
+func caffeineIntake(r *http.Request) (CaffeineIntake, error) {
+	var intake CaffeineIntake
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&intake); err != nil && err != io.EOF {
+			return intake, fmt.Errorf("invalid JSON payload: %w", err)
+		}
+	} else {
+		if raw := r.URL.Query().Get("caffeine"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &intake.Caffeine); err != nil {
+				return intake, fmt.Errorf("caffeine must be a JSON array of {\"time\", \"mg\"} doses: %w", err)
+			}
+		}
+		if raw := r.URL.Query().Get("caffeine_half_life"); raw != "" {
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return intake, fmt.Errorf("caffeine_half_life must be a number of hours")
+			}
+			intake.CaffeineHalfLife = v
+		}
+	}
+
+	if err := intake.Caffeine.Validate(); err != nil {
+		return intake, err
+	}
+	if err := biomodel.ValidateCaffeineHalfLife(intake.CaffeineHalfLife); err != nil {
+		return intake, err
+	}
+	return intake, nil
+}
+
+// HandleCaffeineAdvice (GET or POST) - Latest time for a ?mg= dose (default one coffee) that still
+// lets the user sleep at ?bedtime=RFC3339 (defaults to the predicted natural bedtime), on top of the
+// caffeine they have already had (see CaffeineIntake).
+func (s *Server) HandleCaffeineAdvice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mg := biomodel.DefaultCaffeineDose
+	if raw := r.URL.Query().Get("mg"); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v <= 0 || v > 1000 {
+			http.Error(w, "mg must be a number between 0 and 1000", http.StatusBadRequest)
+			return
+		}
+		mg = v
+	}
+
+	intake, err := caffeineIntake(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := userOrDefault(r.URL.Query().Get("user"))
+	params, _ := s.bioParams(r.Context(), userID, "", time.Now())
+	params.Caffeine = intake.Caffeine
+	params.CaffeineHalfLife = intake.CaffeineHalfLife
+	now := localNow(params)
+
+	var bedtime time.Time
+	if raw := r.URL.Query().Get("bedtime"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			http.Error(w, "bedtime must be an RFC3339 time", http.StatusBadRequest)
+			return
+		}
+		bedtime = t.In(now.Location())
+	} else {
+		window, err := biomodel.PredictSleepWindow(params, now)
+		if err != nil {
+			http.Error(w, "Could not predict bedtime: "+err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		bedtime = window.Bedtime
+	}
+
+	latest, ok := biomodel.LatestCaffeine(params, bedtime, mg)
+
+	response := map[string]interface{}{
+		"user":    userID,
+		"dose_mg": mg,
+		"bedtime": bedtime,
+		"safe":    ok && latest.After(now),
+	}
+	if ok {
+		response["latest_time"] = latest
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// CalibrateRequest carries exactly one questionnaire.
+type CalibrateRequest struct {
+	UserID string                  `json:"user_id"`
+	MEQ    []int                   `json:"meq"`  // Chosen option index for each MEQ item
+	MCTQ   *chronotype.MCTQAnswers `json:"mctq"` // Core MCTQ sleep times
+
+	TimeZone string `json:"time_zone"` // Optional IANA zone, e.g. "Europe/Berlin". Kept if omitted.
+}
+
+// HandleCalibrate (POST) - Scores the MEQ or MCTQ and saves ChronotypeLag / FatigueRate (and an
+// optional time_zone) for the user
+func (s *Server) HandleCalibrate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CalibrateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	var result chronotype.Result
+	var err error
+	switch {
+	case len(req.MEQ) > 0 && req.MCTQ != nil:
+		http.Error(w, "Send either meq or mctq, not both", http.StatusBadRequest)
+		return
+	case len(req.MEQ) > 0:
+		var score int
+		score, err = chronotype.ScoreMEQ(req.MEQ)
+		if err == nil {
+			result, err = chronotype.FromMEQ(score)
+		}
+	case req.MCTQ != nil:
+		result, err = chronotype.FromMCTQ(*req.MCTQ)
+	default:
+		http.Error(w, "Missing questionnaire: send meq or mctq", http.StatusBadRequest)
+		return
+	}
+	if err == nil {
+		err = biomodel.ValidateTimeZone(req.TimeZone)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID := userOrDefault(req.UserID)
+	stored := false
+	if s.repo != nil {
+		profile := storage.UserProfile{
+			UserID:        userID,
+			ChronotypeLag: result.ChronotypeLag,
+			FatigueRate:   result.FatigueRate,
+			Chronotype:    result.Category,
+			TimeZone:      req.TimeZone,
+		}
+		if err := s.repo.SaveProfile(r.Context(), profile); err != nil {
+			http.Error(w, "Failed to save profile: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.curves.Invalidate(userID)
+		stored = true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user":   userID,
+		"result": result,
+		"stored": stored,
+	})
+}
+
+// HandleReportAlertness (POST) - Logs a self-report: {"user_id": "...", "kss": 1-9, "time": RFC3339 (optional)}
+func (s *Server) HandleReportAlertness(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.repo == nil {
+		http.Error(w, "Reports unavailable in offline mode", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req struct {
+		UserID string    `json:"user_id"`
+		KSS    int       `json:"kss"`
+		Time   time.Time `json:"time"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+	if req.Time.IsZero() {
+		req.Time = time.Now()
+	}
+	if _, err := biomodel.ObservationFromKSS(req.Time, req.KSS); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.repo.SaveAlertnessReport(r.Context(), userOrDefault(req.UserID), req.Time, req.KSS); err != nil {
+		http.Error(w, "Failed to save report: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// HandleReportHeart (POST) - Logs a wearable reading: {"user_id": "...", "heart_rate": bpm, "hrv": ms, "time": RFC3339 (optional)}
+func (s *Server) HandleReportHeart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.repo == nil {
+		http.Error(w, "Telemetry unavailable in offline mode", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req struct {
+		UserID    string    `json:"user_id"`
+		HeartRate float64   `json:"heart_rate"`
+		HRV       float64   `json:"hrv"`
+		Time      time.Time `json:"time"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+	if req.HeartRate <= 0 || req.HRV <= 0 {
+		http.Error(w, "heart_rate and hrv must be positive", http.StatusBadRequest)
+		return
+	}
+	if req.Time.IsZero() {
+		req.Time = time.Now()
+	}
+
+	if err := s.repo.SaveHeartMetrics(r.Context(), userOrDefault(req.UserID), req.Time, req.HeartRate, req.HRV); err != nil {
+		http.Error(w, "Failed to save reading: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// HandleFitParams (POST) - Fits the user's params to the last ?days=N days (default 30) of observations
+// and stores them in the profile, so every later schedule uses them. With ?circadian=harmonics it also
+// fits a two-harmonic Process C (e.g. to capture a post-lunch dip).
+func (s *Server) HandleFitParams(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.repo == nil {
+		http.Error(w, "Fitting unavailable in offline mode", http.StatusServiceUnavailable)
+		return
+	}
+
+	days := 30
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 365 {
+			http.Error(w, "days must be an integer between 1 and 365", http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+
+	userID := userOrDefault(r.URL.Query().Get("user"))
+	now := time.Now()
+	since := now.AddDate(0, 0, -days)
+
+	obs, err := s.repo.GetObservations(r.Context(), userID, since)
+	if err != nil {
+		http.Error(w, "Failed to load observations: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// The fit needs the real sleep history behind every observation, not just the last week
+	sleep, err := s.repo.GetSleepLog(r.Context(), userID, since.AddDate(0, 0, -1))
+	if err != nil {
+		http.Error(w, "Failed to load sleep log: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	base, _ := s.bioParams(r.Context(), userID, "", now)
+	if len(sleep) == 0 {
+		// Nothing logged: assume the default routine (on the user's clock) rather than one endless day
+		sleep, err = sleep.Extend(biomodel.DefaultRoutine, since.In(localNow(base).Location()), now)
+		if err != nil {
+			http.Error(w, "Failed to plan sleep: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	base.Sleep = sleep
+
+	fit, err := biomodel.FitParams(base, obs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err := s.repo.SaveFit(r.Context(), userID, fit); err != nil {
+		http.Error(w, "Failed to save fit: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.curves.Invalidate(userID)
+	response := map[string]interface{}{
+		"user": userID,
+		"fit":  fit,
+	}
+
+	// Optionally replace the sinusoid with a fitted 24h + 12h profile (needs more data)
+	if r.URL.Query().Get("circadian") == "harmonics" {
+		base.FatigueRate, base.ChronotypeLag = fit.FatigueRate, fit.ChronotypeLag
+		profile, err := biomodel.FitCircadianProfile(base, obs)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if err := s.repo.SaveCircadianProfile(r.Context(), userID, profile); err != nil {
+			http.Error(w, "Failed to save circadian profile: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		response["circadian"] = profile
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// userModel builds the capacity model for a user. An explicit model name or weights in the request
+// win; otherwise users with fitted weights get the weighted model and everyone else the average.
+func (s *Server) userModel(ctx context.Context, userID string, settings ModelSettings, now, until time.Time) (biomodel.CapacityModel, error) {
+	if err := settings.Itinerary.Validate(); err != nil {
+		return nil, err
+	}
+	if err := settings.Light.Validate(); err != nil {
+		return nil, err
+	}
+	if err := settings.Caffeine.Validate(); err != nil {
+		return nil, err
+	}
+	if err := biomodel.ValidateCaffeineHalfLife(settings.CaffeineHalfLife); err != nil {
+		return nil, err
+	}
+	if err := settings.Ultradian.Validate(); err != nil {
+		return nil, err
+	}
+	if settings.Circadian != nil {
+		if err := settings.Circadian.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if settings.Uncertainty != nil {
+		if err := settings.Uncertainty.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if err := biomodel.ValidateTimeZone(settings.TimeZone); err != nil {
+		return nil, err
+	}
+
+	params, profile := s.bioParams(ctx, userID, settings.TimeZone, now)
+	params.Itinerary = settings.Itinerary
+	params.Light = settings.Light
+	params.Caffeine = settings.Caffeine
+	params.CaffeineHalfLife = settings.CaffeineHalfLife
+	params.Ultradian = settings.Ultradian
+	if settings.Circadian != nil {
+		params.Circadian = *settings.Circadian
+	}
+
+	// The nights ahead are the ones this user's model predicts, with everything above applied
+	params.Sleep = biomodel.PredictNights(params, now, until)
+
+	name := settings.Model
+	w := biomodel.DefaultWeights
+	switch {
+	case settings.Weights != nil:
+		w = *settings.Weights
+	case profile != nil && profile.Weights != nil:
+		w = *profile.Weights
+		if name == "" {
+			name = biomodel.ModelWeighted
+		}
+	}
+	model, err := biomodel.NewCapacityModel(name, params, w)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.MonteCarlo || settings.Uncertainty != nil {
+		spread := uncertainty(profile)
+		if settings.Uncertainty != nil {
+			spread = *settings.Uncertainty
+		}
+		model = biomodel.NewEnsemble(model, spread, 0)
+	}
+
+	// Sample once and share: the next request with the same settings reads the cached curve
+	return s.curves.Curve(userID, model, now, until, biomodel.DefaultCurveResolution)
+}
+
+// uncertainty is how far off a user's FatigueRate and ChronotypeLag may be, given what we know:
+// nothing, a questionnaire, or a fit to their own reports.
+func uncertainty(profile *storage.UserProfile) biomodel.Uncertainty {
+	switch {
+	case profile == nil:
+		return biomodel.DefaultUncertainty
+	case profile.Fit != nil:
+		return biomodel.FittedUncertainty
+	case profile.Chronotype != "":
+		return biomodel.CalibratedUncertainty
+	default:
+		return biomodel.DefaultUncertainty
+	}
+}
+
+// bioParams assembles a user's model: calibrated settings from their profile (defaults if they
+// never calibrated) plus their sleep log up to now (see sleepLog).
+// A non-empty zone overrides the profile's time zone.
+// The profile is returned too (nil if there is none) for callers that need fitted weights.
+func (s *Server) bioParams(ctx context.Context, userID, zone string, now time.Time) (biomodel.BioParams, *storage.UserProfile) {
+	params := biomodel.BioParams{
+		ChronotypeLag: 0.0,
+		FatigueRate:   16.0,
+	}
+
+	var profile *storage.UserProfile
+	if s.repo != nil {
+		var err error
+		profile, err = s.repo.GetProfile(ctx, userID)
+		if err != nil {
+			log.Printf("WARNING: %v. Using default bio-params.\n", err)
+			profile = nil
+		}
+	}
+	if profile != nil {
+		params.ChronotypeLag = profile.ChronotypeLag
+		params.FatigueRate = profile.FatigueRate
+		params.TimeZone = profile.TimeZone
+		if profile.Circadian != nil {
+			params.Circadian = *profile.Circadian
+		}
+	}
+	if zone != "" {
+		params.TimeZone = zone
+	}
+
+	// Unlogged past nights follow the routine on the user's clock
+	params.Sleep = s.sleepLog(ctx, userID, localNow(params).Location(), now)
+	return params, profile
+}
+
+// localNow is the current time in the user's zone (the server's if they have not set one).
+func localNow(params biomodel.BioParams) time.Time {
+	if loc := params.Location(); loc != nil {
+		return time.Now().In(loc)
+	}
+	return time.Now()
+}
+
+// sleepLog loads the last week of sleep for a user and fills the nights they did not log, up to
+// now, from the default 23:00 -> 07:00 routine in loc (all of them without a DB or any history).
+// Nights still to come are left to biomodel.PredictNights: the routine would put every user to
+// bed at 23:00, whatever their chronotype or travel.
+func (s *Server) sleepLog(ctx context.Context, userID string, loc *time.Location, now time.Time) biomodel.SleepLog {
+	since := now.In(loc).AddDate(0, 0, -7)
+
+	var logged biomodel.SleepLog
+	if s.repo != nil {
+		var err error
+		logged, err = s.repo.GetSleepLog(ctx, userID, since)
+		if err != nil {
+			log.Printf("WARNING: Could not load sleep log for %s (%v). Using default routine.\n", userID, err)
+		}
+	}
+
+	full, err := logged.Extend(biomodel.DefaultRoutine, since, now)
+	if err != nil {
+		// The default routine is a constant, so this only happens if it was edited badly.
+		log.Printf("WARNING: Could not plan sleep (%v).\n", err)
+		return logged
+	}
+
+	// Extend plans whole nights, up to tomorrow morning's; keep the routine's only if under way
+	var past biomodel.SleepLog
+	for _, ep := range full {
+		if ep.Start.Before(now) || slices.Contains(logged, ep) {
+			past = append(past, ep)
+		}
+	}
+	return past
+}
+
+// writeValidationError answers 400 with {"error": {"code", "message", "tasks"}}. Errors that are
+// not a biomodel.ValidationError get the generic "invalid_request" code.
+func writeValidationError(w http.ResponseWriter, err error) {
+	verr := &biomodel.ValidationError{Code: "invalid_request", Message: err.Error()}
+	errors.As(err, &verr)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": verr})
+}
+
+// userOrDefault falls back to the demo user when a request does not name one.
+func userOrDefault(userID string) string {
+	if userID == "" {
+		return "user_001"
+	}
+	return userID
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sitanshunandan/tardigo/internal/biomodel"
+)
+
+// jetLagged is the settings of a user who flew in from New York yesterday and still runs on its clock.
+func jetLagged(now time.Time) ModelSettings {
+	return ModelSettings{
+		TimeZone:  "UTC",
+		Itinerary: biomodel.Itinerary{{Arrival: now.Add(-24 * time.Hour), From: "America/New_York", To: "UTC"}},
+	}
+}
+
+// tonight is the first night in the model's log that starts after now.
+func tonight(t *testing.T, model biomodel.CapacityModel, now time.Time) biomodel.SleepEpisode {
+	t.Helper()
+	for _, ep := range model.Biology().Sleep {
+		if !ep.Nap && !ep.Start.Before(now) {
+			return ep
+		}
+	}
+	t.Fatalf("no night after %s in %+v", now.Format(time.RFC3339), model.Biology().Sleep)
+	return biomodel.SleepEpisode{}
+}
+
+func TestUserModelPredictsNightsAhead(t *testing.T) {
+	s := &Server{curves: biomodel.NewCurveCache()}
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	model, err := s.userModel(context.Background(), "", jetLagged(now), now, now.Add(48*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The night ahead is the one the model predicts from the sleep up to now, not the routine's
+	params := model.Biology()
+	var past biomodel.SleepLog
+	for _, ep := range params.Sleep {
+		if ep.Start.Before(now) {
+			past = append(past, ep)
+		}
+	}
+	params.Sleep = past
+	predicted := biomodel.PredictNights(params, now, now.Add(48*time.Hour))
+	if len(predicted) <= len(past) {
+		t.Fatalf("test setup: want a predicted night, got %+v", predicted)
+	}
+	want := predicted[len(past)]
+
+	got := tonight(t, model, now)
+	if !got.Start.Equal(want.Start) || !got.End.Equal(want.End) {
+		t.Errorf("got night %s-%s, want the predicted %s-%s", got.Start.Format("Mon 15:04"), got.End.Format("Mon 15:04"),
+			want.Start.Format("Mon 15:04"), want.End.Format("Mon 15:04"))
+	}
+	if got.Start.Hour() == 23 && got.Start.Minute() == 0 {
+		t.Errorf("a user on New York time is sent to bed at the routine's 23:00")
+	}
+}
+
+func TestHandleOptimizeScheduleSkipsPredictedNight(t *testing.T) {
+	s := &Server{curves: biomodel.NewCurveCache()}
+	now := time.Now().UTC()
+	settings := jetLagged(now)
+	model, err := s.userModel(context.Background(), "", settings, now, now.Add(48*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	night := tonight(t, model, now)
+
+	req := OptimizeRequest{ModelSettings: settings, ScheduleOptions: biomodel.ScheduleOptions{HorizonHours: 48}}
+	for i := 0; i < 12; i++ {
+		req.Tasks = append(req.Tasks, biomodel.Task{Name: "task", Duration: 90, Effort: 5})
+	}
+	body, _ := json.Marshal(req)
+	rec := httptest.NewRecorder()
+	s.HandleOptimizeSchedule(rec, httptest.NewRequest(http.MethodPost, "/schedule/optimize", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+
+	var resp struct {
+		Schedule []biomodel.ScheduleItem `json:"schedule"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range resp.Schedule {
+		start, err := time.Parse(time.RFC3339, item.StartTime)
+		if err != nil {
+			continue // UNSCHEDULED
+		}
+		// Slots are booked out if the user is asleep when they start, so a block may run into
+		// the night by less than a slot
+		end := start.Add(time.Duration(item.Duration) * time.Minute)
+		if start.Before(night.End) && night.Start.Add(biomodel.DefaultSlotMinutes*time.Minute).Before(end) {
+			t.Errorf("%q is booked %s-%s, inside the predicted night %s-%s", item.TaskName,
+				start.Format("Mon 15:04"), end.Format("Mon 15:04"), night.Start.Format("Mon 15:04"), night.End.Format("Mon 15:04"))
+		}
+	}
+}
+
+func TestHandleCaffeineAdviceCountsEarlierIntake(t *testing.T) {
+	s := &Server{curves: biomodel.NewCurveCache()}
+	now := time.Now().UTC()
+	bedtime := now.Add(16 * time.Hour).Truncate(time.Hour)
+	morning, _ := json.Marshal(biomodel.CaffeineLog{{Time: now.Add(-time.Hour), Milligrams: 400}})
+
+	advise := func(t *testing.T, r *http.Request) (latest time.Time) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		s.HandleCaffeineAdvice(rec, r)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", rec.Code, rec.Body)
+		}
+		var resp struct {
+			LatestTime time.Time `json:"latest_time"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.LatestTime
+	}
+	target := "/caffeine/advice?bedtime=" + url.QueryEscape(bedtime.Format(time.RFC3339))
+
+	fresh := advise(t, httptest.NewRequest(http.MethodGet, target, nil))
+	tests := []struct {
+		name string
+		req  *http.Request
+	}{
+		{"query", httptest.NewRequest(http.MethodGet, target+"&caffeine="+url.QueryEscape(string(morning)), nil)},
+		{"body", httptest.NewRequest(http.MethodPost, target, strings.NewReader(`{"caffeine": `+string(morning)+`}`))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := advise(t, tt.req); !got.Before(fresh) {
+				t.Errorf("with 400mg this morning the cutoff is %s, want it before the %s of a fresh day",
+					got.Format("15:04"), fresh.Format("15:04"))
+			}
+		})
+	}
+}
@@ -1,13 +1,20 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"text/tabwriter"
+	"time"
+
+	"github.com/sitanshunandan/tardigo/internal/chronotype"
 )
 
 // Config: Where does the CLI look for the brain?
@@ -15,29 +22,115 @@ const API_URL = "http://127.0.0.1:8080"
 
 // Task structure matches the API expectation
 type Task struct {
-	Name     string `json:"name"`
-	Duration int    `json:"duration_minutes"`
-	Effort   int    `json:"effort_level"`
+	Name       string `json:"name"`
+	Duration   int    `json:"duration_minutes"`
+	Effort     int    `json:"effort_level"`
+	Splittable bool   `json:"splittable,omitempty"`
 }
 
 // Response structures for parsing JSON
+// PlanRequest matches the API's /schedule/optimize payload
+type PlanRequest struct {
+	Model        string  `json:"model,omitempty"`
+	HorizonHours int     `json:"horizon_hours,omitempty"`
+	Events       []Event `json:"events,omitempty"`
+	Breaks       *Breaks `json:"breaks,omitempty"`
+	Tasks        []Task  `json:"tasks"`
+}
+
+// Breaks asks for a recovery break of Minutes after every hard (effort 7+) block
+type Breaks struct {
+	Minutes int `json:"minutes"`
+}
+
+// Event is a fixed block (meeting, lunch) the plan has to work around
+type Event struct {
+	Name   string    `json:"name"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Effort int       `json:"effort_level"`
+}
+
 type ScheduleItem struct {
 	StartTime    string  `json:"start_time"`
 	TaskName     string  `json:"task_name"`
 	PredictedCap float64 `json:"predicted_capacity"`
-	FitScore     string  `json:"fit_score"`
+	Interval     *struct {
+		P10 float64 `json:"p10"`
+		P90 float64 `json:"p90"`
+	} `json:"interval"`
+	FitScore string `json:"fit_score"`
+	Part     int    `json:"part"`
+	Parts    int    `json:"parts"`
+}
+
+type DayPlan struct {
+	Date  string         `json:"date"`
+	Items []ScheduleItem `json:"items"`
 }
 
 type ScheduleResponse struct {
-	Algorithm string         `json:"algorithm"`
-	Schedule  []ScheduleItem `json:"schedule"`
+	Algorithm   string         `json:"algorithm"`
+	Model       string         `json:"model"`
+	Days        []DayPlan      `json:"days"`
+	Unscheduled []ScheduleItem `json:"unscheduled"`
+}
+
+type SleepWindow struct {
+	Bedtime  time.Time `json:"bedtime"`
+	WakeTime time.Time `json:"wake_time"`
+}
+
+type SleepAdviceResponse struct {
+	PredictedSleep SleepWindow `json:"predicted_sleep"`
+	PredictedHours float64     `json:"predicted_hours"`
+	TargetWake     time.Time   `json:"target_wake"`
+	OptimalBedtime time.Time   `json:"optimal_bedtime"`
+	Achievable     bool        `json:"achievable"`
+}
+
+type NapAdviceResponse struct {
+	Recommended bool `json:"recommended"`
+	Nap         struct {
+		Start time.Time `json:"start"`
+		End   time.Time `json:"end"`
+	} `json:"nap"`
+	NapMinutes       float64 `json:"nap_minutes"`
+	BaselineCapacity float64 `json:"baseline_capacity"`
+	BlockCapacity    float64 `json:"block_capacity"`
+}
+
+type CaffeineAdviceResponse struct {
+	DoseMg     float64   `json:"dose_mg"`
+	Bedtime    time.Time `json:"bedtime"`
+	LatestTime time.Time `json:"latest_time"`
+	Safe       bool      `json:"safe"`
+}
+
+type CalibrateRequest struct {
+	MEQ  []int                   `json:"meq,omitempty"`
+	MCTQ *chronotype.MCTQAnswers `json:"mctq,omitempty"`
+}
+
+type CalibrateResponse struct {
+	Result chronotype.Result `json:"result"`
+	Stored bool              `json:"stored"`
 }
 
 type CapacityResponse struct {
 	Status         string             `json:"status"`
+	Time           time.Time          `json:"time"`      // Now on the user's clock
+	TimeZone       string             `json:"time_zone"` // The user's zone, "Local" if the server's
 	CapacityScore  float64            `json:"capacity_score"`
 	Recommendation string             `json:"recommendation"`
 	Components     map[string]float64 `json:"components"`
+	SleepDebt      float64            `json:"sleep_debt_hours"`
+	PeakCapacity   float64            `json:"peak_capacity"`
+	Performance    struct {
+		KSS             float64 `json:"kss"`
+		Lapses          float64 `json:"pvt_lapses"`
+		ErrorMultiplier float64 `json:"error_rate_multiplier"`
+	} `json:"performance"`
 }
 
 func main() {
@@ -53,6 +146,14 @@ func main() {
 		handleStatus()
 	case "plan":
 		handlePlan(os.Args[2:])
+	case "sleep-advice":
+		handleSleepAdvice(os.Args[2:])
+	case "calibrate":
+		handleCalibrate(os.Args[2:])
+	case "caffeine":
+		handleCaffeine(os.Args[2:])
+	case "nap":
+		handleNap(os.Args[2:])
 	default:
 		fmt.Printf("Unknown command: %s\n", command)
 		printUsage()
@@ -63,7 +164,15 @@ func main() {
 func printUsage() {
 	fmt.Println("Usage:")
 	fmt.Println("  tardigo status                  # Get current brain capacity")
-	fmt.Println("  tardigo plan <name> <min> <1-10> # optimize a single task")
+	fmt.Println("  tardigo plan <name> <min> <1-10> [model] [--days N] # optimize a single task")
+	fmt.Println("                                  # --days: plan up to N days ahead (default: the next 12 hours)")
+	fmt.Println("                                  # --event HH:MM-HH:MM[,name[,effort]]: fixed block, repeatable")
+	fmt.Println("                                  # --split: allow 30-90 minute focus blocks; --breaks M: M-minute break after hard blocks")
+	fmt.Println("                                  # model: average | multiplicative | alertness | weighted")
+	fmt.Println("  tardigo sleep-advice [HH:MM]     # when to sleep to wake rested at HH:MM (default 07:00)")
+	fmt.Println("  tardigo calibrate [meq|mctq]     # find your chronotype (interactive)")
+	fmt.Println("  tardigo caffeine [mg] [HH:MM]    # latest safe coffee before bedtime (default: predicted)")
+	fmt.Println("  tardigo nap <HH:MM> <HH:MM>      # best nap before a block you need to be sharp for")
 	fmt.Println("Example:")
 	fmt.Println("  tardigo plan \"Learn Rust\" 60 9")
 }
@@ -86,11 +195,42 @@ func handleStatus() {
 	fmt.Printf("Capacity:       %.2f (%.0f%%)\n", data.CapacityScore, data.CapacityScore*100)
 	fmt.Printf("Freshness (S):  %.2f\n", data.Components["freshness"])
 	fmt.Printf("Circadian (C):  %.2f\n", data.Components["circadian"])
+	fmt.Printf("Sleepiness:     KSS %.1f / 9\n", data.Performance.KSS)
+	fmt.Printf("Vigilance:      ~%.0f lapses per 10-min test\n", data.Performance.Lapses)
+	fmt.Printf("Error rate:     %.1fx your rested baseline\n", data.Performance.ErrorMultiplier)
+	if data.SleepDebt > 0 {
+		fmt.Printf("Sleep debt:     %.1fh (peak capacity %.0f%%)\n", data.SleepDebt, data.PeakCapacity*100)
+	} else {
+		fmt.Println("Sleep debt:     none")
+	}
 	fmt.Printf("Advice:         %s\n", data.Recommendation)
 	fmt.Println("---------------------------")
 }
 
 func handlePlan(args []string) {
+	args, days, err := intFlag(args, "--days")
+	if err != nil || days < 0 || days > 28 {
+		fmt.Println("Error: --days must be a whole number of days up to 28 (0 plans the next 12 hours).")
+		return
+	}
+	args, rawEvents := flagValues(args, "--event")
+	var events []Event
+	// Event clock times are on the user's clock, like the plan that comes back
+	now := userNow()
+	for _, raw := range rawEvents {
+		event, err := parseEvent(raw, now)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		events = append(events, event)
+	}
+	args, breakMinutes, err := intFlag(args, "--breaks")
+	if err != nil || breakMinutes < 0 || (breakMinutes > 0 && breakMinutes < 5) || breakMinutes > 120 {
+		fmt.Println("Error: --breaks must be a number of minutes between 5 and 120.")
+		return
+	}
+	args, split := boolFlag(args, "--split")
 	if len(args) < 3 {
 		fmt.Println("Error: Missing arguments for plan.")
 		printUsage()
@@ -102,17 +242,41 @@ func handlePlan(args []string) {
 	effort, _ := strconv.Atoi(args[2])
 
 	// Construct payload (List of 1 task for now)
-	tasks := []Task{
-		{Name: name, Duration: duration, Effort: effort},
+	req := PlanRequest{
+		Tasks:  []Task{{Name: name, Duration: duration, Effort: effort, Splittable: split}},
+		Events: events,
+	}
+	if breakMinutes > 0 {
+		req.Breaks = &Breaks{Minutes: breakMinutes}
+	}
+	if len(args) > 3 {
+		req.Model = args[3]
+	}
+	if days > 0 {
+		req.HorizonHours = days * 24
 	}
 
-	jsonData, _ := json.Marshal(tasks)
+	jsonData, _ := json.Marshal(req)
 	resp, err := http.Post(API_URL+"/schedule/optimize", "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
 		fmt.Printf("Error calling scheduler: %v\n", err)
 		return
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		// Task problems come back as {"error": {"message": ...}}, anything else as plain text
+		var rejected struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(msg, &rejected) == nil && rejected.Error.Message != "" {
+			msg = []byte(rejected.Error.Message)
+		}
+		fmt.Printf("Scheduler rejected the plan: %s\n", strings.TrimSpace(string(msg)))
+		return
+	}
 
 	var plan ScheduleResponse
 	if err := json.NewDecoder(resp.Body).Decode(&plan); err != nil {
@@ -120,21 +284,385 @@ func handlePlan(args []string) {
 		return
 	}
 
-	fmt.Printf("\n--- 📅 Optimized Schedule (%s) ---\n", plan.Algorithm)
+	fmt.Printf("\n--- 📅 Optimized Schedule (%s, %s model) ---\n", plan.Algorithm, plan.Model)
 
+	for _, day := range plan.Days {
+		if date, err := time.Parse("2006-01-02", day.Date); err == nil && len(plan.Days) > 1 {
+			fmt.Printf("\n%s\n", date.Format("Monday, 2 Jan"))
+		}
+		printSchedule(day.Items)
+	}
+	if len(plan.Unscheduled) > 0 {
+		fmt.Println("\nUnscheduled")
+		printSchedule(plan.Unscheduled)
+	}
+	fmt.Println()
+}
+
+// printSchedule prints schedule items as a table.
+func printSchedule(items []ScheduleItem) {
 	// Use TabWriter for clean columns
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
 	fmt.Fprintln(w, "START\tTASK\tCAPACITY\tFIT\t")
 	fmt.Fprintln(w, "-----\t----\t--------\t---\t")
 
-	for _, item := range plan.Schedule {
-		fmt.Fprintf(w, "%s\t%s\t%.2f\t%s\t\n",
-			item.StartTime,
-			item.TaskName,
-			item.PredictedCap,
+	for _, item := range items {
+		capacity := fmt.Sprintf("%.2f", item.PredictedCap)
+		if item.Interval != nil {
+			capacity += fmt.Sprintf(" (%.2f-%.2f)", item.Interval.P10, item.Interval.P90)
+		}
+		// Start times come back as RFC3339 in the user's zone; show the clock time
+		start := item.StartTime
+		if t, err := time.Parse(time.RFC3339, start); err == nil {
+			start = t.Format("15:04")
+		}
+		// Focus blocks of a split task show which part they are
+		task := item.TaskName
+		if item.Parts > 1 {
+			task += fmt.Sprintf(" (%d/%d)", item.Part, item.Parts)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n",
+			start,
+			task,
+			capacity,
 			item.FitScore,
 		)
 	}
 	w.Flush()
-	fmt.Println()
+}
+
+// flagValues pulls every "--name value" (or "--name=value") out of args, wherever it appears,
+// and returns the remaining arguments alongside the values.
+func flagValues(args []string, name string) ([]string, []string) {
+	var rest, values []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == name && i+1 < len(args):
+			values = append(values, args[i+1])
+			i++
+		case strings.HasPrefix(args[i], name+"="):
+			values = append(values, strings.TrimPrefix(args[i], name+"="))
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return rest, values
+}
+
+// intFlag pulls "--name N" out of args like flagValues. The value is 0 if the flag is absent,
+// and the last one wins if it is repeated.
+func intFlag(args []string, name string) ([]string, int, error) {
+	rest, values := flagValues(args, name)
+	if len(values) == 0 {
+		return rest, 0, nil
+	}
+	n, err := strconv.Atoi(values[len(values)-1])
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return rest, n, nil
+}
+
+// boolFlag pulls a bare "--name" out of args and reports whether it was there.
+func boolFlag(args []string, name string) ([]string, bool) {
+	var rest []string
+	found := false
+	for _, arg := range args {
+		if arg == name {
+			found = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, found
+}
+
+// parseEvent reads "HH:MM-HH:MM[,name[,effort]]" as the next such block after 'after'.
+func parseEvent(raw string, after time.Time) (Event, error) {
+	parts := strings.Split(raw, ",")
+	clocks := strings.Split(parts[0], "-")
+	if len(clocks) != 2 || len(parts) > 3 {
+		return Event{}, fmt.Errorf("event %q must look like HH:MM-HH:MM[,name[,effort]]", raw)
+	}
+
+	start, err := nextClock(clocks[0], after)
+	if err != nil {
+		return Event{}, fmt.Errorf("event %q: %w", raw, err)
+	}
+	end, err := nextClock(clocks[1], start)
+	if err != nil {
+		return Event{}, fmt.Errorf("event %q: %w", raw, err)
+	}
+
+	event := Event{Name: "Busy", Start: start, End: end}
+	if len(parts) > 1 && parts[1] != "" {
+		event.Name = parts[1]
+	}
+	if len(parts) > 2 {
+		if event.Effort, err = strconv.Atoi(parts[2]); err != nil {
+			return Event{}, fmt.Errorf("event %q: effort must be a number 0-10", raw)
+		}
+	}
+	return event, nil
+}
+
+func handleSleepAdvice(args []string) {
+	wakeClock := "07:00"
+	if len(args) > 0 {
+		wakeClock = args[0]
+	}
+
+	target, err := nextClock(wakeClock, userNow())
+	if err != nil {
+		fmt.Printf("Error: wake time must look like 07:00, got %q\n", wakeClock)
+		return
+	}
+
+	resp, err := http.Get(API_URL + "/sleep/advice?wake=" + url.QueryEscape(target.Format(time.RFC3339)))
+	if err != nil {
+		fmt.Printf("Error connecting to Cortex: %v\n", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	var data SleepAdviceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		fmt.Printf("Error parsing response: %v\n", err)
+		return
+	}
+
+	fmt.Println("\n--- 🌙 Sleep Advice ---")
+	fmt.Printf("Natural night:  %s -> %s (%.1fh)\n",
+		data.PredictedSleep.Bedtime.Format("Mon 15:04"),
+		data.PredictedSleep.WakeTime.Format("Mon 15:04"),
+		data.PredictedHours,
+	)
+	fmt.Printf("To wake at %s: ", data.TargetWake.Format("Mon 15:04"))
+	if data.Achievable {
+		fmt.Printf("be asleep by %s\n", data.OptimalBedtime.Format("15:04"))
+	} else {
+		fmt.Println("go to bed now, you will still wake up tired")
+	}
+	fmt.Println("---------------------------")
+}
+
+func handleCaffeine(args []string) {
+	query := url.Values{}
+	if len(args) > 0 {
+		if _, err := strconv.ParseFloat(args[0], 64); err != nil {
+			fmt.Printf("Error: dose must be a number of mg, got %q\n", args[0])
+			return
+		}
+		query.Set("mg", args[0])
+	}
+	if len(args) > 1 {
+		bedtime, err := nextClock(args[1], userNow())
+		if err != nil {
+			fmt.Printf("Error: bedtime must look like 23:00, got %q\n", args[1])
+			return
+		}
+		query.Set("bedtime", bedtime.Format(time.RFC3339))
+	}
+
+	resp, err := http.Get(API_URL + "/caffeine/advice?" + query.Encode())
+	if err != nil {
+		fmt.Printf("Error connecting to Cortex: %v\n", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	var data CaffeineAdviceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		fmt.Printf("Error parsing response: %v\n", err)
+		return
+	}
+
+	fmt.Println("\n--- ☕ Caffeine Advice ---")
+	fmt.Printf("Bedtime:        %s\n", data.Bedtime.Format("Mon 15:04"))
+	if data.Safe {
+		fmt.Printf("Last %.0fmg by:  %s\n", data.DoseMg, data.LatestTime.Format("15:04"))
+	} else {
+		fmt.Printf("Skip it: %.0fmg now would still be in your system at bedtime\n", data.DoseMg)
+	}
+	fmt.Println("---------------------------")
+}
+
+func handleNap(args []string) {
+	if len(args) < 2 {
+		fmt.Println("Error: nap needs the start and end of the block to be sharp for.")
+		printUsage()
+		return
+	}
+
+	now := userNow()
+	start, err := nextClock(args[0], now)
+	if err != nil {
+		fmt.Printf("Error: block start must look like 18:00, got %q\n", args[0])
+		return
+	}
+	end, err := nextClock(args[1], start)
+	if err != nil {
+		fmt.Printf("Error: block end must look like 22:00, got %q\n", args[1])
+		return
+	}
+
+	query := url.Values{}
+	query.Set("start", start.Format(time.RFC3339))
+	query.Set("end", end.Format(time.RFC3339))
+	resp, err := http.Get(API_URL + "/sleep/nap?" + query.Encode())
+	if err != nil {
+		fmt.Printf("Error connecting to Cortex: %v\n", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	var data NapAdviceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		fmt.Printf("Error parsing response: %v\n", err)
+		return
+	}
+
+	fmt.Println("\n--- 💤 Nap Advice ---")
+	fmt.Printf("Block:          %s -> %s\n", start.Format("Mon 15:04"), end.Format("15:04"))
+	if data.Recommended {
+		fmt.Printf("Nap:            %s for %.0f min\n", data.Nap.Start.Format("15:04"), data.NapMinutes)
+		fmt.Printf("Capacity:       %.2f -> %.2f\n", data.BaselineCapacity, data.BlockCapacity)
+	} else {
+		fmt.Printf("No nap needed (capacity %.2f either way)\n", data.BaselineCapacity)
+	}
+	fmt.Println("---------------------------")
+}
+
+// userNow returns now on the user's clock, in the time zone of their profile, so clock times
+// typed here mean the same to Cortex wherever the CLI runs. If Cortex cannot be asked, it falls
+// back to this machine's clock.
+func userNow() time.Time {
+	now := time.Now()
+	resp, err := http.Get(API_URL + "/capacity/now")
+	if err != nil {
+		return now
+	}
+	defer resp.Body.Close()
+
+	var data CapacityResponse
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil || data.Time.IsZero() {
+		return now
+	}
+	// Prefer the named zone so a date across a DST change still resolves right; the server's
+	// own zone ("Local") only comes through as the offset in the time
+	if data.TimeZone != "" && data.TimeZone != "Local" {
+		if loc, err := time.LoadLocation(data.TimeZone); err == nil {
+			return now.In(loc)
+		}
+	}
+	return now.In(data.Time.Location())
+}
+
+// nextClock returns the next occurrence of a "15:04" wall-clock time after 'after'.
+func nextClock(clock string, after time.Time) (time.Time, error) {
+	c, err := time.Parse("15:04", clock)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t := time.Date(after.Year(), after.Month(), after.Day(), c.Hour(), c.Minute(), 0, 0, after.Location())
+	if !t.After(after) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func handleCalibrate(args []string) {
+	method := "meq"
+	if len(args) > 0 {
+		method = strings.ToLower(args[0])
+	}
+
+	in := bufio.NewScanner(os.Stdin)
+	var req CalibrateRequest
+
+	switch method {
+	case "meq":
+		fmt.Printf("\n--- 🧬 Morningness-Eveningness Questionnaire (%d questions) ---\n", len(chronotype.MEQItems))
+		for i, item := range chronotype.MEQItems {
+			fmt.Printf("\n%d. %s\n", i+1, item.Question)
+			for j, opt := range item.Options {
+				fmt.Printf("   [%d] %s\n", j+1, opt.Label)
+			}
+			choice, ok := askChoice(in, len(item.Options))
+			if !ok {
+				fmt.Println("Calibration cancelled.")
+				return
+			}
+			req.MEQ = append(req.MEQ, choice)
+		}
+	case "mctq":
+		fmt.Println("\n--- 🧬 Munich ChronoType Questionnaire (times as HH:MM) ---")
+		days, err := strconv.Atoi(ask(in, "Work days per week: "))
+		if err != nil {
+			fmt.Println("Error: work days must be a number.")
+			return
+		}
+		req.MCTQ = &chronotype.MCTQAnswers{
+			WorkDaysPerWeek: days,
+			WorkSleepOnset:  ask(in, "Work days - fall asleep at: "),
+			WorkSleepEnd:    ask(in, "Work days - wake up at: "),
+			FreeSleepOnset:  ask(in, "Free days - fall asleep at: "),
+			FreeSleepEnd:    ask(in, "Free days - wake up (no alarm) at: "),
+		}
+	default:
+		fmt.Printf("Unknown questionnaire: %s (use meq or mctq)\n", method)
+		return
+	}
+
+	jsonData, _ := json.Marshal(req)
+	resp, err := http.Post(API_URL+"/calibrate", "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		fmt.Printf("Error calling calibration: %v\n", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		fmt.Printf("Calibration rejected (%s)\n", resp.Status)
+		return
+	}
+
+	var data CalibrateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		fmt.Printf("Error parsing response: %v\n", err)
+		return
+	}
+
+	fmt.Println("\n--- 🧬 Your Chronotype ---")
+	fmt.Printf("Type:           %s (%s score %.2f)\n", data.Result.Category, data.Result.Method, data.Result.Score)
+	fmt.Printf("Circadian lag:  %+.1fh\n", data.Result.ChronotypeLag)
+	fmt.Printf("Fatigue rate:   %.1f\n", data.Result.FatigueRate)
+	if !data.Stored {
+		fmt.Println("(Cortex is offline from the DB: parameters were not saved)")
+	}
+	fmt.Println("---------------------------")
+}
+
+// ask prints a prompt and returns the trimmed line typed by the user.
+func ask(in *bufio.Scanner, prompt string) string {
+	fmt.Print(prompt)
+	if !in.Scan() {
+		return ""
+	}
+	return strings.TrimSpace(in.Text())
+}
+
+// askChoice keeps asking until the user picks an option between 1 and n.
+// It returns the zero-based index, or false if input ends.
+func askChoice(in *bufio.Scanner, n int) (int, bool) {
+	for {
+		fmt.Printf("   Choice (1-%d): ", n)
+		if !in.Scan() {
+			return 0, false
+		}
+		choice, err := strconv.Atoi(strings.TrimSpace(in.Text()))
+		if err == nil && choice >= 1 && choice <= n {
+			return choice - 1, true
+		}
+	}
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -24,10 +25,38 @@ func main() {
 	// 2. Define the Tool
 	// We use the basic constructor and then manually enhance the schema for the complex 'tasks' array
 	scheduleTool := mcp.NewTool("plan_biological_schedule",
-		mcp.WithDescription("Generates an optimal daily schedule based on biological energy. Use this to plan tasks."),
+		mcp.WithDescription("Generates an optimal schedule based on biological energy, grouped by day. Tasks can depend on each other via id/depends_on. Use this to plan tasks."),
 		mcp.WithString("wake_time",
 			mcp.Required(),
-			mcp.Description("The time the user woke up today (RFC3339 format, e.g. 2026-02-17T07:00:00Z)."),
+			mcp.Description("The time the user woke up today (RFC3339 format, e.g. 2026-02-17T07:00:00+01:00)."),
+		),
+		mcp.WithString("time_zone",
+			mcp.Description("IANA time zone the user lives in, e.g. 'Europe/Berlin'. Circadian phase and the returned start times use it. Defaults to wake_time's offset."),
+		),
+		mcp.WithNumber("chronotype_lag",
+			mcp.Description("Hours the user's circadian rhythm is shifted (from 'tardigo calibrate'). + = night owl. Defaults to 0."),
+		),
+		mcp.WithNumber("fatigue_rate",
+			mcp.Description("Time constant (hours) of sleep pressure build-up (from 'tardigo calibrate'). Defaults to 16."),
+		),
+		mcp.WithString("model",
+			mcp.Description("How to combine sleep pressure, circadian drive and inertia into capacity. Defaults to 'average'."),
+			mcp.Enum(biomodel.ModelAverage, biomodel.ModelMultiplicative, biomodel.ModelAlertness, biomodel.ModelWeighted),
+		),
+		mcp.WithNumber("caffeine_half_life",
+			mcp.Description("Hours it takes the user to clear half their caffeine, 0.5-30. Defaults to 5."),
+		),
+		mcp.WithBoolean("allow_nap",
+			mcp.Description("Insert a 'Nap' item when a nap improves the overall schedule. Defaults to false."),
+		),
+		mcp.WithNumber("slot_minutes",
+			mcp.Description("Planning granularity in minutes: 5, 10, 15 or 30. Defaults to 30; task durations are rounded up to whole slots."),
+		),
+		mcp.WithNumber("horizon_hours",
+			mcp.Description("How many hours ahead to plan, up to four weeks (672). Defaults to 12. Longer horizons spread tasks over several days and skip the nights."),
+		),
+		mcp.WithBoolean("pessimistic",
+			mcp.Description("Sample fatigue_rate and chronotype_lag and plan on the p10 capacity band. Each item then carries its mean/p10/p90 interval."),
 		),
 	)
 
@@ -38,13 +67,167 @@ func main() {
 		"items": map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
+				"id":               map[string]interface{}{"type": "string", "description": "Unique ID, so other tasks can depend on this one"},
 				"name":             map[string]interface{}{"type": "string"},
-				"duration_minutes": map[string]interface{}{"type": "integer"},
-				"effort_level":     map[string]interface{}{"type": "integer", "description": "1-10 scale"},
+				"duration_minutes": map[string]interface{}{"type": "integer", "minimum": 1},
+				"effort_level":     map[string]interface{}{"type": "integer", "minimum": 1, "maximum": 10, "description": "1-10 scale"},
+				"earliest_start":   map[string]interface{}{"type": "string", "description": "RFC3339. Do not start before this."},
+				"deadline":         map[string]interface{}{"type": "string", "description": "RFC3339. Must be finished by this."},
+				"windows": map[string]interface{}{
+					"type":        "array",
+					"description": "Allowed time windows; the task runs entirely inside one of them.",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"start": map[string]interface{}{"type": "string", "description": "RFC3339"},
+							"end":   map[string]interface{}{"type": "string", "description": "RFC3339"},
+						},
+						"required": []string{"start", "end"},
+					},
+				},
+				"depends_on": map[string]interface{}{
+					"type":        "array",
+					"description": "IDs of tasks that must be finished before this one starts.",
+					"items":       map[string]interface{}{"type": "string"},
+				},
+				"splittable":        map[string]interface{}{"type": "boolean", "description": "May be booked as several focus blocks in different windows."},
+				"min_chunk_minutes": map[string]interface{}{"type": "integer", "minimum": 1, "description": "Shortest focus block of a splittable task (default 30)."},
+				"max_chunk_minutes": map[string]interface{}{"type": "integer", "minimum": 1, "description": "Longest focus block of a splittable task (default 90)."},
 			},
 			"required": []string{"name", "duration_minutes", "effort_level"},
 		},
 	}
+	// Per-user weights, only read by the 'weighted' model
+	scheduleTool.InputSchema.Properties["weights"] = map[string]interface{}{
+		"type":        "object",
+		"description": "Weights for the 'weighted' model.",
+		"properties": map[string]interface{}{
+			"homeostatic": map[string]interface{}{"type": "number", "description": "Weight of sleep-pressure freshness"},
+			"circadian":   map[string]interface{}{"type": "number", "description": "Weight of circadian drive"},
+			"inertia":     map[string]interface{}{"type": "number", "description": "Capacity lost right after waking"},
+		},
+	}
+	// Trips across time zones, so the body clock lags realistically on conference days
+	scheduleTool.InputSchema.Properties["itinerary"] = map[string]interface{}{
+		"type":        "array",
+		"description": "Recent or upcoming trips across time zones. The circadian rhythm re-entrains gradually after each arrival.",
+		"items": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"arrival": map[string]interface{}{"type": "string", "description": "Arrival time (RFC3339)"},
+				"from":    map[string]interface{}{"type": "string", "description": "IANA zone left, e.g. Europe/Berlin"},
+				"to":      map[string]interface{}{"type": "string", "description": "IANA zone arrived in, e.g. America/Los_Angeles"},
+			},
+			"required": []string{"arrival", "from", "to"},
+		},
+	}
+	// Light exposure drives the optional circadian oscillator (night shifts, late screens)
+	scheduleTool.InputSchema.Properties["light"] = map[string]interface{}{
+		"type":        "array",
+		"description": "Light exposure log. When given, the circadian rhythm is simulated from light instead of a fixed curve.",
+		"items": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"start": map[string]interface{}{"type": "string", "description": "Start (RFC3339)"},
+				"end":   map[string]interface{}{"type": "string", "description": "End (RFC3339)"},
+				"lux":   map[string]interface{}{"type": "number", "description": "Measured illuminance, if known"},
+				"kind":  map[string]interface{}{"type": "string", "enum": []string{"outdoors", "indoors", "screen", "dark"}},
+			},
+			"required": []string{"start", "end"},
+		},
+	}
+	// Caffeine temporarily masks sleep pressure
+	scheduleTool.InputSchema.Properties["caffeine"] = map[string]interface{}{
+		"type":        "array",
+		"description": "Caffeine intake (past or planned) for the day.",
+		"items": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"time": map[string]interface{}{"type": "string", "description": "Intake time (RFC3339)"},
+				"mg":   map[string]interface{}{"type": "number", "description": "Dose in mg (a coffee is ~95)"},
+			},
+			"required": []string{"time", "mg"},
+		},
+	}
+	// Ultradian focus waves, so deep work lands on the crest of a cycle
+	scheduleTool.InputSchema.Properties["ultradian"] = map[string]interface{}{
+		"type":        "object",
+		"description": "The user's ~90 minute Basic Rest-Activity Cycle. Off unless amplitude is set.",
+		"properties": map[string]interface{}{
+			"period_minutes": map[string]interface{}{"type": "number", "description": "Cycle length, 60-150. Defaults to 90."},
+			"amplitude":      map[string]interface{}{"type": "number", "description": "Capacity swing either side, 0-0.2"},
+		},
+	}
+	// Shape of the circadian rhythm
+	scheduleTool.InputSchema.Properties["circadian"] = map[string]interface{}{
+		"type":        "object",
+		"description": "Shape of the circadian rhythm. Set one of preset, harmonics or curve; defaults to a single sinusoid.",
+		"properties": map[string]interface{}{
+			"preset": map[string]interface{}{
+				"type": "string",
+				"enum": []string{biomodel.CircadianSine, biomodel.CircadianPostLunchDip},
+			},
+			"harmonics": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"period_hours": map[string]interface{}{"type": "number"},
+						"amplitude":    map[string]interface{}{"type": "number"},
+						"peak_hour":    map[string]interface{}{"type": "number"},
+					},
+				},
+			},
+			"curve": map[string]interface{}{
+				"type":        "array",
+				"description": "Alertness samples evenly spaced over 24h, starting at midnight",
+				"items":       map[string]interface{}{"type": "number"},
+			},
+		},
+	}
+	// Monte Carlo spread and a floor for hard work
+	scheduleTool.InputSchema.Properties["uncertainty"] = map[string]interface{}{
+		"type":        "object",
+		"description": "Standard deviations of fatigue_rate and chronotype_lag for Monte Carlo sampling. Enables intervals.",
+		"properties": map[string]interface{}{
+			"fatigue_rate_sd":   map[string]interface{}{"type": "number", "description": "Hours, default 2.5"},
+			"chronotype_lag_sd": map[string]interface{}{"type": "number", "description": "Hours, default 1.5"},
+		},
+	}
+	scheduleTool.InputSchema.Properties["band_floor"] = map[string]interface{}{
+		"type":        "object",
+		"description": "Book tasks of at least 'effort' only where p10 capacity is at least 'p10', e.g. {effort: 9, p10: 0.7}.",
+		"properties": map[string]interface{}{
+			"effort": map[string]interface{}{"type": "integer"},
+			"p10":    map[string]interface{}{"type": "number"},
+		},
+		"required": []string{"effort", "p10"},
+	}
+	// Fixed calendar events the plan works around
+	scheduleTool.InputSchema.Properties["events"] = map[string]interface{}{
+		"type":        "array",
+		"description": "Fixed events (meetings, lunch) whose time is blocked. Events with an effort also tire the user.",
+		"items": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"name":         map[string]interface{}{"type": "string"},
+				"start":        map[string]interface{}{"type": "string", "description": "RFC3339"},
+				"end":          map[string]interface{}{"type": "string", "description": "RFC3339"},
+				"effort_level": map[string]interface{}{"type": "integer", "minimum": 0, "maximum": 10, "description": "0 = blocked time that costs no energy"},
+			},
+			"required": []string{"name", "start", "end"},
+		},
+	}
+	// Mandatory recovery after hard work
+	scheduleTool.InputSchema.Properties["breaks"] = map[string]interface{}{
+		"type":        "object",
+		"description": "Book a break of 'minutes' after every block of at least 'effort' (default 7). Breaks appear as 'Break' items.",
+		"properties": map[string]interface{}{
+			"minutes": map[string]interface{}{"type": "integer", "minimum": 5, "maximum": 120},
+			"effort":  map[string]interface{}{"type": "integer", "minimum": 1, "maximum": 10},
+		},
+		"required": []string{"minutes"},
+	}
 	// Add 'tasks' to the required list
 	scheduleTool.InputSchema.Required = append(scheduleTool.InputSchema.Required, "tasks")
 
@@ -59,8 +242,27 @@ func main() {
 		}
 
 		var args struct {
-			WakeTime string          `json:"wake_time"`
-			Tasks    []biomodel.Task `json:"tasks"`
+			WakeTime      string                    `json:"wake_time"`
+			TimeZone      string                    `json:"time_zone"`
+			ChronotypeLag float64                   `json:"chronotype_lag"`
+			FatigueRate   float64                   `json:"fatigue_rate"`
+			Model         string                    `json:"model"`
+			Weights       *biomodel.Weights         `json:"weights"`
+			Itinerary     biomodel.Itinerary        `json:"itinerary"`
+			Light         biomodel.LightLog         `json:"light"`
+			Caffeine      biomodel.CaffeineLog      `json:"caffeine"`
+			HalfLife      float64                   `json:"caffeine_half_life"`
+			Ultradian     biomodel.Ultradian        `json:"ultradian"`
+			Circadian     biomodel.CircadianProfile `json:"circadian"`
+			AllowNap      bool                      `json:"allow_nap"`
+			SlotMinutes   int                       `json:"slot_minutes"`
+			HorizonHours  int                       `json:"horizon_hours"`
+			Events        []biomodel.Event          `json:"events"`
+			Breaks        *biomodel.BreakPolicy     `json:"breaks"`
+			Pessimistic   bool                      `json:"pessimistic"`
+			BandFloor     *biomodel.BandFloor       `json:"band_floor"`
+			Uncertainty   *biomodel.Uncertainty     `json:"uncertainty"`
+			Tasks         []biomodel.Task           `json:"tasks"`
 		}
 
 		if err := json.Unmarshal(jsonArgs, &args); err != nil {
@@ -73,20 +275,82 @@ func main() {
 			return mcp.NewToolResultError("Invalid wake_time format. Use RFC3339 (e.g., 2026-02-17T07:00:00Z)."), nil
 		}
 
+		if err := biomodel.ValidateTimeZone(args.TimeZone); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if err := args.Itinerary.Validate(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid itinerary: %v", err)), nil
+		}
+		if err := args.Light.Validate(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid light log: %v", err)), nil
+		}
+		if err := args.Caffeine.Validate(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid caffeine log: %v", err)), nil
+		}
+		if err := biomodel.ValidateCaffeineHalfLife(args.HalfLife); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if err := args.Ultradian.Validate(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid ultradian rhythm: %v", err)), nil
+		}
+		if err := args.Circadian.Validate(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid circadian profile: %v", err)), nil
+		}
+		if err := biomodel.ValidateTasks(args.Tasks); err != nil {
+			return validationError(err), nil
+		}
+		if err := args.BandFloor.Validate(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid band floor: %v", err)), nil
+		}
+		if args.Uncertainty != nil {
+			if err := args.Uncertainty.Validate(); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Invalid uncertainty: %v", err)), nil
+			}
+		}
+
 		// C. Setup World Model
 		bioParams := biomodel.BioParams{
-			WakeTime:      wakeTime,
-			ChronotypeLag: 0.0,
-			FatigueRate:   16.0,
+			WakeTime:         wakeTime,
+			TimeZone:         args.TimeZone,
+			ChronotypeLag:    args.ChronotypeLag,
+			FatigueRate:      args.FatigueRate, // Zero falls back to biomodel.DefaultFatigueRate
+			Itinerary:        args.Itinerary,
+			Light:            args.Light,
+			Caffeine:         args.Caffeine,
+			CaffeineHalfLife: args.HalfLife,
+			Ultradian:        args.Ultradian,
+			Circadian:        args.Circadian,
+		}
+
+		weights := biomodel.DefaultWeights
+		if args.Weights != nil {
+			weights = *args.Weights
+		}
+		model, err := biomodel.NewCapacityModel(args.Model, bioParams, weights)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if args.Uncertainty != nil {
+			model = biomodel.NewEnsemble(model, *args.Uncertainty, 0)
 		}
 
 		// D. Run Scheduler
-		// Schedule starting 1 hour after wake time
-		startSim := wakeTime.Add(1 * time.Hour)
-		schedule := biomodel.OptimizeSchedule(args.Tasks, startSim, bioParams)
+		// Schedule starting at wake time. Sleep inertia keeps hard tasks out of the groggy first hour.
+		schedule, err := biomodel.OptimizeSchedule(args.Tasks, wakeTime, model, biomodel.ScheduleOptions{
+			AllowNap:     args.AllowNap,
+			SlotMinutes:  args.SlotMinutes,
+			HorizonHours: args.HorizonHours,
+			Events:       args.Events,
+			Breaks:       args.Breaks,
+			Pessimistic:  args.Pessimistic,
+			BandFloor:    args.BandFloor,
+		})
+		if err != nil {
+			return validationError(err), nil
+		}
 
-		// E. Return Result
-		responseBytes, _ := json.MarshalIndent(schedule, "", "  ")
+		// E. Return Result, grouped per day
+		responseBytes, _ := json.MarshalIndent(biomodel.GroupByDay(schedule), "", "  ")
 		return mcp.NewToolResultText(string(responseBytes)), nil
 	})
 
@@ -96,3 +360,12 @@ func main() {
 		fmt.Printf("Server error: %v\n", err)
 	}
 }
+
+// validationError reports a rejected request as JSON, {"error": {"code", "message", "tasks"}}, so the
+// caller can see which tasks to fix (e.g. the ones in a dependency cycle).
+func validationError(err error) *mcp.CallToolResult {
+	verr := &biomodel.ValidationError{Code: "invalid_request", Message: err.Error()}
+	errors.As(err, &verr)
+	body, _ := json.MarshalIndent(map[string]interface{}{"error": verr}, "", "  ")
+	return mcp.NewToolResultError(string(body))
+}
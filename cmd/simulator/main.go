@@ -34,26 +34,53 @@ func main() {
 
 	// 3. Setup Bio-Model
 	now := time.Now()
-	// Simulating a user who woke up at 7 AM
-	wakeTime := time.Date(now.Year(), now.Month(), now.Day(), 7, 0, 0, 0, now.Location())
+	// Simulating a user who follows the default sleep routine (last night plus tonight)
+	sleepLog, err := biomodel.DefaultRoutine.Nights(now, 1)
+	if err != nil {
+		fmt.Printf("CRITICAL: Could not plan sleep: %v\n", err)
+		os.Exit(1)
+	}
+	wakeTime, ok := sleepLog.LastWake(now)
+	if !ok {
+		// Still inside (or before) the first night, so start from its wake-up instead
+		wakeTime = sleepLog[0].End
+	}
 
 	params := biomodel.BioParams{
-		WakeTime:      wakeTime,
+		Sleep:         sleepLog,
 		ChronotypeLag: 0.0,
 		FatigueRate:   16.0,
 	}
 
 	userID := "user_001" // hardcoded for simulation
 
+	// Use the user's calibrated chronotype if they have one
+	if profile, err := repo.GetProfile(ctx, userID); err != nil {
+		fmt.Printf("WARNING: Could not load profile, using defaults: %v\n", err)
+	} else if profile != nil {
+		params.ChronotypeLag = profile.ChronotypeLag
+		params.FatigueRate = profile.FatigueRate
+	}
+
+	// Store the nights too, so the API integrates the same sleep we simulated
+	for _, ep := range sleepLog {
+		if err := repo.SaveSleepEpisode(ctx, userID, ep); err != nil {
+			fmt.Printf("ERROR writing sleep episode: %v\n", err)
+		}
+	}
+
 	// 4. The Loop: Generate & Ingest Data
 	fmt.Println(">>> Ingesting 24 hours of biometric data...")
 
+	// Sample the whole day in one pass, the same way the API and scheduler do
+	curve := biomodel.NewCapacityCurve(biomodel.AverageModel{Params: params}, wakeTime, wakeTime.Add(23*time.Hour), time.Hour)
+
 	for i := 0; i < 24; i++ {
 		// Simulate time moving forward hour by hour
 		simTime := wakeTime.Add(time.Duration(i) * time.Hour)
 
 		// A. Calculate Logic
-		state := params.CalculateState(simTime)
+		state := curve.State(simTime)
 
 		// B. Persistence Logic (The new part!)
 		err := repo.Save(ctx, userID, simTime, state)
@@ -61,7 +88,7 @@ func main() {
 			fmt.Printf("ERROR writing data: %v\n", err)
 		} else {
 			// Visual feedback in logs
-			fmt.Printf("[SAVED] %s | Capacity: %.2f\n", simTime.Format("15:04"), state.TotalCapacity)
+			fmt.Printf("[SAVED] %s | Capacity: %.2f | Asleep: %v\n", simTime.Format("15:04"), state.TotalCapacity, state.Asleep)
 		}
 	}
 
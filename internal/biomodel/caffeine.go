@@ -0,0 +1,126 @@
+package biomodel
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Caffeine pharmacokinetics (one-compartment, first-order absorption and elimination)
+// and its masking effect on sleep pressure.
+const (
+	DefaultCaffeineHalfLife = 5.0   // Hours. Typical adult elimination half-life.
+	DefaultCaffeineDose     = 95.0  // mg. One cup of brewed coffee.
+	caffeineAbsorption      = 2.0   // 1/h. Plasma peak roughly 45-60 minutes after intake.
+	caffeineEC50            = 100.0 // mg in the body for half the maximum effect
+	caffeineMaxMask         = 0.5   // At most, caffeine hides half of the sleep pressure
+
+	// Residual caffeine that does not measurably disturb sleep, checked over the first hours of the night
+	caffeineSafeAtSleep  = 50.0 // mg
+	caffeineSleepWindow  = 3 * time.Hour
+	caffeineAdviceStep   = 15 * time.Minute
+	caffeineAdviceWindow = 24 * time.Hour
+)
+
+// CaffeineDose is a single intake.
+type CaffeineDose struct {
+	Time       time.Time `json:"time"`
+	Milligrams float64   `json:"mg"`
+}
+
+// CaffeineLog is the user's recent and planned intake.
+type CaffeineLog []CaffeineDose
+
+// Validate rejects non-positive doses.
+func (l CaffeineLog) Validate() error {
+	for _, d := range l {
+		if d.Milligrams <= 0 {
+			return fmt.Errorf("caffeine dose at %s must be positive", d.Time.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// Plausible caffeine half-lives in hours: fast metabolisers (e.g. smokers) clear it in about
+// two, pregnancy or oral contraceptives can stretch it past ten.
+const (
+	minCaffeineHalfLife = 0.5
+	maxCaffeineHalfLife = 30.0
+)
+
+// ValidateCaffeineHalfLife checks a half-life in hours. Zero is valid and means the default.
+func ValidateCaffeineHalfLife(hours float64) error {
+	if hours == 0 {
+		return nil
+	}
+	if hours < minCaffeineHalfLife || hours > maxCaffeineHalfLife {
+		return fmt.Errorf("caffeine_half_life must be between %.1f and %.0f hours, got %.2f", minCaffeineHalfLife, maxCaffeineHalfLife, hours)
+	}
+	return nil
+}
+
+// caffeineLevel returns the caffeine in the body (mg) at t:
+//
+//	A(t) = Σ D · ka/(ka - ke) · (e^(-ke·t) - e^(-ka·t))
+//
+// When absorption and elimination run at the same rate (a half-life of ln2/ka, about 21 minutes)
+// this is 0/0, and the limit is A(t) = Σ D · ka · t · e^(-ka·t). Close to it, the difference
+// of exponentials is computed with Expm1 so it does not lose precision.
+func (b *BioParams) caffeineLevel(t time.Time) float64 {
+	halfLife := b.CaffeineHalfLife
+	if halfLife <= 0 {
+		halfLife = DefaultCaffeineHalfLife
+	}
+	ke := math.Ln2 / halfLife
+	ka := caffeineAbsorption
+
+	total := 0.0
+	for _, dose := range b.Caffeine {
+		hours := t.Sub(dose.Time).Hours()
+		if hours <= 0 {
+			continue
+		}
+		total += dose.Milligrams * absorbed(ka, ke, hours)
+	}
+	return total
+}
+
+// absorbed is the fraction of a dose in the body after the given hours:
+// ka/(ka - ke) · (e^(-ke·t) - e^(-ka·t)), or ka · t · e^(-ka·t) when ka == ke.
+func absorbed(ka, ke, hours float64) float64 {
+	diff := ka - ke
+	if diff == 0 {
+		return ka * hours * math.Exp(-ka*hours)
+	}
+	// e^(-ke·t) - e^(-ka·t) = -e^(-ke·t) · (e^(-(ka-ke)·t) - 1)
+	return -ka / diff * math.Exp(-ke*hours) * math.Expm1(-diff*hours)
+}
+
+// caffeineEffect converts the body level into a 0-1 effect with an Emax curve.
+func (b *BioParams) caffeineEffect(t time.Time) float64 {
+	level := b.caffeineLevel(t)
+	return level / (level + caffeineEC50)
+}
+
+// LatestCaffeine returns the latest time before bedtime at which a dose of the given size keeps
+// residual caffeine under the safe level for the first hours of sleep, on top of what the user
+// has already had. It returns false if nothing in the preceding day is safe.
+func LatestCaffeine(params BioParams, bedtime time.Time, mg float64) (time.Time, bool) {
+	for t := bedtime; t.After(bedtime.Add(-caffeineAdviceWindow)); t = t.Add(-caffeineAdviceStep) {
+		candidate := params
+		candidate.Caffeine = append(append(CaffeineLog{}, params.Caffeine...), CaffeineDose{Time: t, Milligrams: mg})
+		if candidate.peakCaffeine(bedtime, bedtime.Add(caffeineSleepWindow)) <= caffeineSafeAtSleep {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// peakCaffeine is the highest body level between from and to.
+func (b *BioParams) peakCaffeine(from, to time.Time) float64 {
+	peak := 0.0
+	for t := from; !t.After(to); t = t.Add(caffeineAdviceStep) {
+		peak = math.Max(peak, b.caffeineLevel(t))
+	}
+	return peak
+}
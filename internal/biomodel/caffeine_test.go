@@ -0,0 +1,32 @@
+package biomodel
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAbsorbedIsContinuousAtEqualRates(t *testing.T) {
+	ka := caffeineAbsorption
+	limit := absorbed(ka, ka, 1.5)
+	if math.IsNaN(limit) || math.IsInf(limit, 0) {
+		t.Fatalf("absorbed(ka, ka) = %v", limit)
+	}
+	for _, eps := range []float64{1e-3, 1e-9, 1e-14} {
+		if got := absorbed(ka, ka-eps, 1.5); math.Abs(got-limit) > 1e-3 {
+			t.Errorf("absorbed with ke = ka - %g: got %v, want about %v", eps, got, limit)
+		}
+	}
+}
+
+func TestValidateCaffeineHalfLife(t *testing.T) {
+	for _, hours := range []float64{0, 0.5, 5, 30} {
+		if err := ValidateCaffeineHalfLife(hours); err != nil {
+			t.Errorf("ValidateCaffeineHalfLife(%v) = %v, want nil", hours, err)
+		}
+	}
+	for _, hours := range []float64{-1, 0.1, 31} {
+		if err := ValidateCaffeineHalfLife(hours); err == nil {
+			t.Errorf("ValidateCaffeineHalfLife(%v) = nil, want an error", hours)
+		}
+	}
+}
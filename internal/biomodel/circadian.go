@@ -0,0 +1,259 @@
+package biomodel
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// Circadian profile presets accepted in CircadianProfile.Preset.
+const (
+	CircadianSine         = "sine"           // The classic single sinusoid (the default)
+	CircadianPostLunchDip = "post_lunch_dip" // Adds a 12h harmonic that carves out the early-afternoon dip
+)
+
+// Circadian shape constants.
+const (
+	circadianScanStep        = 0.25 // Hours. Resolution used to find a profile's range for normalization.
+	MinCircadianObservations = 24   // Fewer cannot pin down two harmonics plus sleep pressure
+	maxCurvePoints           = 288  // One sample every 5 minutes
+)
+
+// Harmonic is one cosine component of Process C.
+//
+//	A * cos(2π * (hour - Peak) / Period)
+type Harmonic struct {
+	Period    float64 `json:"period_hours"` // 24 for the fundamental, 12 for the post-lunch dip component
+	Amplitude float64 `json:"amplitude"`    // Relative size; only the ratio between harmonics matters
+	Peak      float64 `json:"peak_hour"`    // Clock hour of the component's (first) maximum
+}
+
+// postLunchDip has the classic two-peak day: best around 09:30, a dip around 14:45 (to ~60% of
+// the range) and a second, lower peak around 19:30 just before the evening wake-maintenance zone
+// gives way to the night.
+var postLunchDip = []Harmonic{
+	{Period: 24, Amplitude: 1.0, Peak: 13.5},
+	{Period: 12, Amplitude: 0.9, Peak: 8.5},
+}
+
+// CircadianProfile replaces the default sinusoid of Process C with either a preset, a sum of
+// harmonics or a lookup curve sampled from the user's own data. Whatever the shape, it is
+// normalized to span 0-1 and shifted by ChronotypeLag like the sinusoid.
+// The zero value is the default sinusoid.
+type CircadianProfile struct {
+	Preset    string     `json:"preset,omitempty"`
+	Harmonics []Harmonic `json:"harmonics,omitempty"`
+	Curve     []float64  `json:"curve,omitempty"` // Evenly spaced over 24h, starting at midnight
+
+	// Range of the shape over the day, worked out once when the profile is decoded or fitted
+	// (see normalized) rather than on every evaluation.
+	span *circadianSpan
+}
+
+// circadianSpan is the lowest and highest raw value of a profile over the day.
+type circadianSpan struct {
+	lo, hi float64
+}
+
+// postLunchDipSpan is the preset's range, so hand-built preset profiles need no scan either.
+var postLunchDipSpan = CircadianProfile{Harmonics: postLunchDip}.scan()
+
+// UnmarshalJSON decodes a profile and normalizes it, so profiles from requests and the database
+// arrive ready to evaluate.
+func (p *CircadianProfile) UnmarshalJSON(data []byte) error {
+	type plain CircadianProfile // Without this method, so Unmarshal does not recurse
+	var decoded plain
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*p = CircadianProfile(decoded).normalized()
+	return nil
+}
+
+// normalized returns the profile with its range over the day worked out. Change the shape
+// afterwards and the range must be worked out again.
+func (p CircadianProfile) normalized() CircadianProfile {
+	p.span = nil
+	if p.custom() {
+		p.span = p.scan()
+	}
+	return p
+}
+
+// scan finds the profile's range by sampling the day every circadianScanStep hours.
+func (p CircadianProfile) scan() *circadianSpan {
+	span := &circadianSpan{lo: math.Inf(1), hi: math.Inf(-1)}
+	for h := 0.0; h < 24; h += circadianScanStep {
+		v := p.raw(h)
+		span.lo, span.hi = math.Min(span.lo, v), math.Max(span.hi, v)
+	}
+	return span
+}
+
+// Validate checks that the profile describes exactly one usable shape.
+func (p CircadianProfile) Validate() error {
+	shapes := 0
+	if p.Preset != "" {
+		shapes++
+		if p.Preset != CircadianSine && p.Preset != CircadianPostLunchDip {
+			return fmt.Errorf("unknown circadian preset %q", p.Preset)
+		}
+	}
+	if len(p.Harmonics) > 0 {
+		shapes++
+		for _, h := range p.Harmonics {
+			if h.Period <= 0 || h.Period > 24 {
+				return fmt.Errorf("harmonic period must be between 0 and 24 hours, got %.1f", h.Period)
+			}
+			if h.Amplitude < 0 {
+				return fmt.Errorf("harmonic amplitude must not be negative, got %.2f", h.Amplitude)
+			}
+		}
+	}
+	if len(p.Curve) > 0 {
+		shapes++
+		if len(p.Curve) < 4 || len(p.Curve) > maxCurvePoints {
+			return fmt.Errorf("circadian curve needs between 4 and %d points, got %d", maxCurvePoints, len(p.Curve))
+		}
+	}
+	if shapes > 1 {
+		return fmt.Errorf("circadian profile must set only one of preset, harmonics or curve")
+	}
+	return nil
+}
+
+// harmonics returns the harmonics the profile stands for, or nil for the default sinusoid and curves.
+func (p CircadianProfile) harmonics() []Harmonic {
+	if p.Preset == CircadianPostLunchDip {
+		return postLunchDip
+	}
+	return p.Harmonics
+}
+
+// custom reports whether the profile replaces the default sinusoid.
+func (p CircadianProfile) custom() bool {
+	return len(p.harmonics()) > 0 || len(p.Curve) > 0
+}
+
+// at returns normalized Process C (0.0 to 1.0) at the given body-clock hour.
+func (p CircadianProfile) at(hour float64) float64 {
+	span := p.span
+	switch {
+	case span != nil:
+	case p.Preset == CircadianPostLunchDip:
+		span = postLunchDipSpan
+	default:
+		span = p.scan() // Built by hand and never normalized
+	}
+	lo, hi := span.lo, span.hi
+	if hi <= lo {
+		return 0.5 // Flat profile: no time-of-day effect
+	}
+	return clamp01((p.raw(hour) - lo) / (hi - lo))
+}
+
+// raw evaluates the un-normalized shape at a clock hour.
+func (p CircadianProfile) raw(hour float64) float64 {
+	hour = math.Mod(math.Mod(hour, 24)+24, 24)
+
+	if len(p.Curve) > 0 {
+		// Linear interpolation, wrapping from the last sample back to midnight
+		pos := hour / 24 * float64(len(p.Curve))
+		i := int(pos) % len(p.Curve)
+		j := (i + 1) % len(p.Curve)
+		frac := pos - math.Floor(pos)
+		return p.Curve[i]*(1-frac) + p.Curve[j]*frac
+	}
+
+	sum := 0.0
+	for _, h := range p.harmonics() {
+		sum += h.Amplitude * math.Cos(2*math.Pi*(hour-h.Peak)/h.Period)
+	}
+	return sum
+}
+
+// FitCircadianProfile fits a 24h + 12h harmonic profile to observations by weighted least squares,
+// with sleep pressure as a covariate so the slow build-up of S is not mistaken for circadian drive:
+//
+//	alertness = b0 + bS*S + Σ (a_k cos(ω_k h) + c_k sin(ω_k h)),   h = body-clock hour - ChronotypeLag
+//
+// Hours are measured relative to base.ChronotypeLag, so the profile reproduces the data once the
+// lag is applied again.
+func FitCircadianProfile(base BioParams, obs []Observation) (CircadianProfile, error) {
+	if len(obs) < MinCircadianObservations {
+		return CircadianProfile{}, fmt.Errorf("need at least %d observations to fit a circadian profile, got %d", MinCircadianObservations, len(obs))
+	}
+
+	periods := []float64{24, 12}
+	const n = 6 // Intercept, S, and a cos/sin pair per period
+
+	var xtx [n][n]float64
+	var xty [n]float64
+	for _, o := range obs {
+		hour := base.bodyClockHour(o.Time) - base.ChronotypeLag
+		x := [n]float64{1, base.components(o.Time).ProcessS}
+		for k, period := range periods {
+			x[2+2*k] = math.Cos(2 * math.Pi * hour / period)
+			x[3+2*k] = math.Sin(2 * math.Pi * hour / period)
+		}
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				xtx[i][j] += o.Weight * x[i] * x[j]
+			}
+			xty[i] += o.Weight * x[i] * o.Alertness
+		}
+	}
+
+	beta, err := solveLinear(xtx, xty)
+	if err != nil {
+		return CircadianProfile{}, fmt.Errorf("observations do not cover the day well enough: %w", err)
+	}
+
+	var profile CircadianProfile
+	for k, period := range periods {
+		a, c := beta[2+2*k], beta[3+2*k]
+		peak := math.Atan2(c, a) / (2 * math.Pi) * period
+		profile.Harmonics = append(profile.Harmonics, Harmonic{
+			Period:    period,
+			Amplitude: math.Hypot(a, c),
+			Peak:      math.Mod(peak+period, period),
+		})
+	}
+	return profile.normalized(), nil
+}
+
+// solveLinear solves the 6x6 system A x = b by Gaussian elimination with partial pivoting.
+func solveLinear(a [6][6]float64, b [6]float64) ([6]float64, error) {
+	const n = 6
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return [n]float64{}, fmt.Errorf("singular system")
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+
+		for row := col + 1; row < n; row++ {
+			f := a[row][col] / a[col][col]
+			for k := col; k < n; k++ {
+				a[row][k] -= f * a[col][k]
+			}
+			b[row] -= f * b[col]
+		}
+	}
+
+	var x [n]float64
+	for row := n - 1; row >= 0; row-- {
+		sum := b[row]
+		for k := row + 1; k < n; k++ {
+			sum -= a[row][k] * x[k]
+		}
+		x[row] = sum / a[row][row]
+	}
+	return x, nil
+}
@@ -0,0 +1,26 @@
+package biomodel
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestCircadianProfileNormalizedOnDecode(t *testing.T) {
+	var decoded CircadianProfile
+	if err := json.Unmarshal([]byte(`{"curve": [0.2, 0.5, 0.9, 0.7, 0.4, 0.1]}`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.span == nil {
+		t.Fatal("decoded profile has no cached range")
+	}
+	byHand := CircadianProfile{Curve: decoded.Curve}
+	for hour := 0.0; hour < 24; hour += 0.5 {
+		if got, want := decoded.at(hour), byHand.at(hour); math.Abs(got-want) > 1e-12 {
+			t.Errorf("at(%.1f) = %v with the cached range, %v without", hour, got, want)
+		}
+	}
+	if lo, hi := decoded.at(20), decoded.at(8); lo != 0 || hi != 1 {
+		t.Errorf("curve should span 0-1 at its extremes, got %v and %v", lo, hi)
+	}
+}
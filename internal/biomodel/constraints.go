@@ -0,0 +1,80 @@
+package biomodel
+
+import (
+	"fmt"
+	"time"
+)
+
+// InfeasibleFitScore marks tasks whose own constraints rule out every start in the horizon.
+const InfeasibleFitScore = "Infeasible"
+
+// validateConstraints checks the task's earliest start, deadline and windows are well formed.
+// Whether they can be met is only known when planning.
+func (t Task) validateConstraints() error {
+	if t.EarliestStart != nil && t.Deadline != nil && !t.Deadline.After(*t.EarliestStart) {
+		return fmt.Errorf("task %q: deadline %s is not after earliest_start %s", t.Name,
+			t.Deadline.Format(time.RFC3339), t.EarliestStart.Format(time.RFC3339))
+	}
+	for _, w := range t.Windows {
+		if !w.End.After(w.Start) {
+			return fmt.Errorf("task %q: window starting %s does not end after it starts", t.Name, w.Start.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// constrained reports whether the task has any timing constraint.
+func (t Task) constrained() bool {
+	return t.EarliestStart != nil || t.Deadline != nil || len(t.Windows) > 0
+}
+
+// fits reports whether the task may run from start: not before its earliest start, finished by
+// its deadline and entirely inside one of its windows (if it has any).
+func (t Task) fits(start time.Time) bool {
+	end := start.Add(time.Duration(t.Duration) * time.Minute)
+	if t.EarliestStart != nil && start.Before(*t.EarliestStart) {
+		return false
+	}
+	if t.Deadline != nil && end.After(*t.Deadline) {
+		return false
+	}
+	if len(t.Windows) == 0 {
+		return true
+	}
+	for _, w := range t.Windows {
+		if !start.Before(w.Start) && !end.After(w.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// infeasibility explains why none of the plan's starts between from and until fits the task's
+// constraints, before anything else is booked. Times are shown in from's zone.
+func (t Task) infeasibility(from, until time.Time) string {
+	length := time.Duration(t.Duration) * time.Minute
+	clock := func(ts time.Time) string { return ts.In(from.Location()).Format("Mon 15:04") }
+
+	earliest := from
+	if t.EarliestStart != nil && t.EarliestStart.After(earliest) {
+		earliest = *t.EarliestStart
+	}
+	latestEnd := until
+	if t.Deadline != nil && t.Deadline.Before(latestEnd) {
+		latestEnd = *t.Deadline
+	}
+
+	switch {
+	case t.EarliestStart != nil && !t.EarliestStart.Before(until):
+		return fmt.Sprintf("earliest start %s is after the plan ends at %s", clock(*t.EarliestStart), clock(until))
+	case t.Deadline != nil && t.Deadline.Before(from.Add(length)):
+		return fmt.Sprintf("deadline %s is less than %d minutes after the plan starts at %s", clock(*t.Deadline), t.Duration, clock(from))
+	case earliest.Add(length).After(latestEnd):
+		return fmt.Sprintf("only %.0f minutes between %s and %s, but the task needs %d",
+			latestEnd.Sub(earliest).Minutes(), clock(earliest), clock(latestEnd), t.Duration)
+	case len(t.Windows) > 0:
+		return fmt.Sprintf("none of its windows leaves %d minutes between %s and %s", t.Duration, clock(earliest), clock(latestEnd))
+	default:
+		return fmt.Sprintf("no slot between %s and %s lets it start on time", clock(earliest), clock(latestEnd))
+	}
+}
@@ -0,0 +1,52 @@
+package biomodel
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllocateExplainsInfeasibleConstraints(t *testing.T) {
+	start := time.Date(2026, 3, 10, 8, 0, 0, 0, time.UTC) // The plan runs 08:00-12:00
+	at := func(hour, minute int) *time.Time {
+		ts := time.Date(2026, 3, 10, hour, minute, 0, 0, time.UTC)
+		return &ts
+	}
+
+	tests := []struct {
+		name   string
+		task   Task
+		reason string
+	}{
+		{"earliest start after the plan", Task{EarliestStart: at(13, 0)},
+			"earliest start Tue 13:00 is after the plan ends at Tue 12:00"},
+		{"deadline too soon", Task{Deadline: at(8, 30)},
+			"deadline Tue 08:30 is less than 60 minutes after the plan starts at Tue 08:00"},
+		{"earliest start and deadline too close", Task{EarliestStart: at(10, 0), Deadline: at(10, 45)},
+			"only 45 minutes between Tue 10:00 and Tue 10:45, but the task needs 60"},
+		{"window too short", Task{Windows: []TimeBlock{{Start: *at(9, 0), End: *at(9, 45)}}},
+			"none of its windows leaves 60 minutes between Tue 08:00 and Tue 12:00"},
+		{"no slot starts on time", Task{EarliestStart: at(9, 10), Deadline: at(10, 20)},
+			"no slot between Tue 09:10 and Tue 10:20 lets it start on time"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := tt.task
+			task.Name, task.Duration, task.Effort = "review", 60, 5
+			if err := task.Validate(); err != nil {
+				t.Fatalf("test setup: %v", err)
+			}
+
+			schedule, _ := allocate([]Task{task}, flatSlots(start, 0.8, 0.8, 0.8, 0.8, 0.8, 0.8, 0.8, 0.8), -1, ScheduleOptions{})
+			if len(schedule) != 1 {
+				t.Fatalf("want one item, got %+v", schedule)
+			}
+			item := schedule[0]
+			if item.StartTime != "UNSCHEDULED" || item.FitScore != InfeasibleFitScore {
+				t.Errorf("got %s with fit %q, want UNSCHEDULED and %q", item.StartTime, item.FitScore, InfeasibleFitScore)
+			}
+			if item.Reason != tt.reason {
+				t.Errorf("reason %q, want %q", item.Reason, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package biomodel
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultCurveResolution is fine enough that interpolating between samples is indistinguishable
+// from evaluating the model (capacity moves by well under 0.01 in five minutes).
+const DefaultCurveResolution = 5 * time.Minute
+
+// CapacityCurve is a CapacityModel evaluated once over a time range on a fixed grid.
+// Planners read the same slots many times (every task, every nap candidate), and an Ensemble
+// costs one evaluation per member, so sampling up front and interpolating afterwards is much
+// cheaper than asking the model again. Outside the range it falls back to the model.
+type CapacityCurve struct {
+	Model      CapacityModel
+	Start      time.Time
+	Resolution time.Duration
+
+	states []BioState
+	bands  []CapacityBand // Only set when Model is an Ensemble
+}
+
+// NewCapacityCurve samples model every resolution from 'from' up to and including 'to'.
+// An Ensemble's bands are sampled in the same pass.
+func NewCapacityCurve(model CapacityModel, from, to time.Time, resolution time.Duration) *CapacityCurve {
+	if resolution <= 0 {
+		resolution = DefaultCurveResolution
+	}
+	model = unwrapCurve(model)
+	ensemble, _ := model.(*Ensemble)
+
+	n := int(to.Sub(from)/resolution) + 1
+	if n < 1 {
+		n = 1
+	}
+	c := &CapacityCurve{
+		Model:      model,
+		Start:      from,
+		Resolution: resolution,
+		states:     make([]BioState, n),
+	}
+	if ensemble != nil {
+		c.bands = make([]CapacityBand, n)
+	}
+	for i := range c.states {
+		t := from.Add(time.Duration(i) * resolution)
+		c.states[i] = model.State(t)
+		if ensemble != nil {
+			c.bands[i] = ensemble.Band(t)
+		}
+	}
+	return c
+}
+
+// End is the time of the last sample.
+func (c *CapacityCurve) End() time.Time {
+	return c.Start.Add(time.Duration(len(c.states)-1) * c.Resolution)
+}
+
+// covers reports whether from..to lies inside the sampled range.
+func (c *CapacityCurve) covers(from, to time.Time) bool {
+	return !from.Before(c.Start) && !to.After(c.End())
+}
+
+// locate returns the sample at or before t and how far t is toward the next one (0 to 1).
+// ok is false outside the range.
+func (c *CapacityCurve) locate(t time.Time) (int, float64, bool) {
+	if t.Before(c.Start) || t.After(c.End()) {
+		return 0, 0, false
+	}
+	offset := t.Sub(c.Start)
+	i := int(offset / c.Resolution)
+	frac := float64(offset%c.Resolution) / float64(c.Resolution)
+	if i == len(c.states)-1 {
+		frac = 0
+	}
+	return i, frac, true
+}
+
+func (c *CapacityCurve) Name() string       { return c.Model.Name() }
+func (c *CapacityCurve) Biology() BioParams { return c.Model.Biology() }
+
+// WithBiology returns the underlying model with different parameters. The samples describe the
+// old parameters, so the result is not a curve.
+func (c *CapacityCurve) WithBiology(params BioParams) CapacityModel {
+	return c.Model.WithBiology(params)
+}
+
+// State interpolates linearly between the samples either side of targetTime.
+// Asleep comes from the nearer sample.
+func (c *CapacityCurve) State(targetTime time.Time) BioState {
+	i, frac, ok := c.locate(targetTime)
+	if !ok {
+		return c.Model.State(targetTime)
+	}
+	if frac == 0 {
+		return c.states[i]
+	}
+
+	a, b := c.states[i], c.states[i+1]
+	state := BioState{
+		ProcessS:      lerp(a.ProcessS, b.ProcessS, frac),
+		ProcessC:      lerp(a.ProcessC, b.ProcessC, frac),
+		ProcessW:      lerp(a.ProcessW, b.ProcessW, frac),
+		Ultradian:     lerp(a.Ultradian, b.Ultradian, frac),
+		Caffeine:      lerp(a.Caffeine, b.Caffeine, frac),
+		SleepDebt:     lerp(a.SleepDebt, b.SleepDebt, frac),
+		TotalCapacity: lerp(a.TotalCapacity, b.TotalCapacity, frac),
+		Asleep:        a.Asleep,
+	}
+	if frac >= 0.5 {
+		state.Asleep = b.Asleep
+	}
+	return state
+}
+
+// Band interpolates the Monte Carlo band at targetTime. It reports false if the model is not an Ensemble.
+func (c *CapacityCurve) Band(targetTime time.Time) (CapacityBand, bool) {
+	if c.bands == nil {
+		return CapacityBand{}, false
+	}
+	i, frac, ok := c.locate(targetTime)
+	if !ok {
+		return c.Model.(*Ensemble).Band(targetTime), true
+	}
+	if frac == 0 {
+		return c.bands[i], true
+	}
+	a, b := c.bands[i], c.bands[i+1]
+	return CapacityBand{
+		Mean: lerp(a.Mean, b.Mean, frac),
+		P10:  lerp(a.P10, b.P10, frac),
+		P90:  lerp(a.P90, b.P90, frac),
+	}, true
+}
+
+func lerp(a, b, frac float64) float64 {
+	return a + (b-a)*frac
+}
+
+// curveFor reuses model if it already is a curve over from..to, and samples a new one otherwise.
+func curveFor(model CapacityModel, from, to time.Time, resolution time.Duration) *CapacityCurve {
+	if c, ok := model.(*CapacityCurve); ok && c.covers(from, to) {
+		return c
+	}
+	return NewCapacityCurve(model, from, to, resolution)
+}
+
+// unwrapCurve returns the model a curve was sampled from (or the model itself).
+func unwrapCurve(model CapacityModel) CapacityModel {
+	if c, ok := model.(*CapacityCurve); ok {
+		return c.Model
+	}
+	return model
+}
+
+// Per-user cache size. Each curve differs by its parameters (sleep log, caffeine, ...), so a user
+// only needs the few variants their recent requests used.
+const curveCacheEntries = 8
+
+// CurveCache shares sampled curves between requests, per user and parameter set.
+// It is safe for concurrent use. Sampling happens outside the lock, so a long horizon being
+// sampled for one user does not hold up anyone else.
+type CurveCache struct {
+	mu       sync.Mutex
+	users    map[string][]cachedCurve // Oldest first
+	sampling map[string]chan struct{} // Curves being sampled, by user and key; closed when done
+}
+
+type cachedCurve struct {
+	key   string
+	curve *CapacityCurve
+}
+
+// NewCurveCache creates an empty cache.
+func NewCurveCache() *CurveCache {
+	return &CurveCache{users: make(map[string][]cachedCurve), sampling: make(map[string]chan struct{})}
+}
+
+// Curve returns a curve of model covering from..to, sampling it only if no cached curve with the
+// same model and parameters covers the range. The grid is aligned to the resolution, so requests
+// made a few minutes apart share their samples.
+func (cc *CurveCache) Curve(userID string, model CapacityModel, from, to time.Time, resolution time.Duration) (*CapacityCurve, error) {
+	if resolution <= 0 {
+		resolution = DefaultCurveResolution
+	}
+	model = unwrapCurve(model)
+	key, err := fingerprint(model, resolution)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. Look for a cached curve. If the same one is being sampled, wait for it: it may cover us.
+	flight := userID + "|" + key
+	cc.mu.Lock()
+	for {
+		if curve := cc.cached(userID, key, from, to); curve != nil {
+			cc.mu.Unlock()
+			return curve, nil
+		}
+		done, busy := cc.sampling[flight]
+		if !busy {
+			break
+		}
+		cc.mu.Unlock()
+		<-done
+		cc.mu.Lock()
+	}
+	done := make(chan struct{})
+	cc.sampling[flight] = done
+	cc.mu.Unlock()
+
+	// 3. Keep the curve, and let anyone waiting look again (even if sampling panicked)
+	var curve *CapacityCurve
+	defer func() {
+		cc.mu.Lock()
+		defer cc.mu.Unlock()
+		delete(cc.sampling, flight)
+		close(done)
+		if curve == nil {
+			return
+		}
+		entries := append(cc.users[userID], cachedCurve{key: key, curve: curve})
+		if len(entries) > curveCacheEntries {
+			entries = entries[len(entries)-curveCacheEntries:]
+		}
+		cc.users[userID] = entries
+	}()
+
+	// 2. Sample without the lock, rounding the range outward onto the grid
+	start := from.Truncate(resolution)
+	end := to.Add(resolution - 1).Truncate(resolution)
+	curve = NewCapacityCurve(model, start, end, resolution)
+	return curve, nil
+}
+
+// cached returns the user's curve with the key covering from..to, or nil. cc.mu must be held.
+func (cc *CurveCache) cached(userID, key string, from, to time.Time) *CapacityCurve {
+	for _, entry := range cc.users[userID] {
+		if entry.key == key && entry.curve.covers(from, to) {
+			return entry.curve
+		}
+	}
+	return nil
+}
+
+// Invalidate drops a user's curves, e.g. after their sleep log or profile changed.
+func (cc *CurveCache) Invalidate(userID string) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	delete(cc.users, userID)
+}
+
+// fingerprint identifies a model and everything it evaluates: its kind, parameters, weights and,
+// for an Ensemble, the sampled draws.
+func fingerprint(model CapacityModel, resolution time.Duration) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%T|%d|", model.Name(), model, resolution)
+
+	if e, ok := model.(*Ensemble); ok {
+		fmt.Fprintf(h, "%v|", e.draws)
+		model = e.Central
+		fmt.Fprintf(h, "%T|", model)
+	}
+	encoded, err := json.Marshal(model)
+	if err != nil {
+		return "", fmt.Errorf("failed to fingerprint %s model: %w", model.Name(), err)
+	}
+	h.Write(encoded)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
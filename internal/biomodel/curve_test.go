@@ -0,0 +1,192 @@
+package biomodel
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+var curveDay = time.Date(2026, 3, 10, 7, 0, 0, 0, time.UTC)
+
+func curveModel(fatigue float64) CapacityModel {
+	sleep, _ := DefaultRoutine.Nights(curveDay, 1)
+	return AverageModel{Params: BioParams{Sleep: sleep, FatigueRate: fatigue}}
+}
+
+func TestCapacityCurveInterpolatesBetweenSamples(t *testing.T) {
+	model := curveModel(16)
+	curve := NewCapacityCurve(model, curveDay, curveDay.Add(12*time.Hour), time.Hour)
+
+	// On the grid the curve is the model
+	at := curveDay.Add(3 * time.Hour)
+	if got, want := curve.State(at).TotalCapacity, model.State(at).TotalCapacity; got != want {
+		t.Errorf("on a sample: got %v, want %v", got, want)
+	}
+
+	// A quarter of the way between two samples is a quarter of the way between their values
+	a, b := curve.State(at), curve.State(at.Add(time.Hour))
+	mid := curve.State(at.Add(15 * time.Minute))
+	if want := a.TotalCapacity + (b.TotalCapacity-a.TotalCapacity)/4; math.Abs(mid.TotalCapacity-want) > 1e-12 {
+		t.Errorf("between samples: got %v, want %v", mid.TotalCapacity, want)
+	}
+
+	// Outside the range it falls back to the model
+	after := curveDay.Add(20 * time.Hour)
+	if got, want := curve.State(after), model.State(after); got != want {
+		t.Errorf("outside the range: got %+v, want %+v", got, want)
+	}
+}
+
+func TestCurveCacheHitsAndMisses(t *testing.T) {
+	cache := NewCurveCache()
+	model := curveModel(16)
+
+	first, err := cache.Curve("alice", model, curveDay, curveDay.Add(12*time.Hour), DefaultCurveResolution)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		user     string
+		model    CapacityModel
+		from, to time.Time
+		hit      bool
+	}{
+		{"same request", "alice", model, curveDay, curveDay.Add(12 * time.Hour), true},
+		{"range inside the cached one", "alice", model, curveDay.Add(time.Hour), curveDay.Add(6 * time.Hour), true},
+		{"range past the cached one", "alice", model, curveDay, curveDay.Add(30 * time.Hour), false},
+		{"other user", "bob", model, curveDay, curveDay.Add(12 * time.Hour), false},
+		{"params changed", "alice", curveModel(14), curveDay, curveDay.Add(12 * time.Hour), false},
+		{"model changed", "alice", MultiplicativeModel{Params: model.Biology()}, curveDay, curveDay.Add(12 * time.Hour), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cache.Curve(tt.user, tt.model, tt.from, tt.to, DefaultCurveResolution)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hit := got == first; hit != tt.hit {
+				t.Errorf("cache hit = %v, want %v", hit, tt.hit)
+			}
+			if got.State(tt.from) != tt.model.State(tt.from) {
+				t.Errorf("curve does not describe the requested model")
+			}
+		})
+	}
+}
+
+func TestCurveCacheInvalidate(t *testing.T) {
+	cache := NewCurveCache()
+	model := curveModel(16)
+	first, _ := cache.Curve("alice", model, curveDay, curveDay.Add(12*time.Hour), DefaultCurveResolution)
+	cache.Invalidate("alice")
+	if again, _ := cache.Curve("alice", model, curveDay, curveDay.Add(12*time.Hour), DefaultCurveResolution); again == first {
+		t.Error("curve survived Invalidate")
+	}
+}
+
+// slowModel blocks in State until released, to catch a curve in the middle of sampling.
+type slowModel struct {
+	AverageModel
+	started, release chan struct{}
+	once             *sync.Once
+}
+
+func newSlowModel() slowModel {
+	return slowModel{AverageModel: curveModel(16).(AverageModel), started: make(chan struct{}), release: make(chan struct{}), once: &sync.Once{}}
+}
+
+func (m slowModel) State(t time.Time) BioState {
+	m.once.Do(func() { close(m.started) })
+	<-m.release
+	return m.AverageModel.State(t)
+}
+
+func TestCurveCacheSamplesOutsideTheLock(t *testing.T) {
+	cache := NewCurveCache()
+	slow := newSlowModel()
+
+	// Alice's curve is stuck sampling, and a second request for it waits for the first
+	curves := make(chan *CapacityCurve, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			curve, _ := cache.Curve("alice", slow, curveDay, curveDay.Add(12*time.Hour), DefaultCurveResolution)
+			curves <- curve
+		}()
+	}
+	<-slow.started
+
+	// Meanwhile Bob is served
+	served := make(chan struct{})
+	go func() {
+		cache.Curve("bob", curveModel(16), curveDay, curveDay.Add(12*time.Hour), DefaultCurveResolution)
+		close(served)
+	}()
+	select {
+	case <-served:
+	case <-time.After(5 * time.Second):
+		t.Fatal("bob's request waited for alice's curve to be sampled")
+	}
+
+	close(slow.release)
+	if a, b := <-curves, <-curves; a == nil || a != b {
+		t.Errorf("concurrent requests for the same curve got %p and %p, want one shared curve", a, b)
+	}
+}
+
+func TestFingerprintChangesWithParams(t *testing.T) {
+	base, err := fingerprint(curveModel(16), DefaultCurveResolution)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caffeinated := curveModel(16).Biology()
+	caffeinated.Caffeine = CaffeineLog{{Time: curveDay, Milligrams: 95}}
+
+	for name, model := range map[string]CapacityModel{
+		"fatigue rate": curveModel(15),
+		"caffeine":     AverageModel{Params: caffeinated},
+		"model kind":   MultiplicativeModel{Params: curveModel(16).Biology()},
+	} {
+		if key, _ := fingerprint(model, DefaultCurveResolution); key == base {
+			t.Errorf("%s changed but the fingerprint did not", name)
+		}
+	}
+	if key, _ := fingerprint(curveModel(16), time.Hour); key == base {
+		t.Error("resolution changed but the fingerprint did not")
+	}
+	if key, _ := fingerprint(curveModel(16), DefaultCurveResolution); key != base {
+		t.Error("the same model fingerprints differently")
+	}
+}
+
+// BenchmarkNewCapacityCurve samples a day on the default 5-minute grid.
+func BenchmarkNewCapacityCurve(b *testing.B) {
+	model := curveModel(16)
+	for i := 0; i < b.N; i++ {
+		NewCapacityCurve(model, curveDay, curveDay.Add(24*time.Hour), DefaultCurveResolution)
+	}
+}
+
+// BenchmarkModelStatePerSlot is the cost the curve replaces: asking the model for every 30-minute
+// slot of a day, as the scheduler did for each pass over the slots.
+func BenchmarkModelStatePerSlot(b *testing.B) {
+	model := curveModel(16)
+	for i := 0; i < b.N; i++ {
+		for t := curveDay; t.Before(curveDay.Add(24 * time.Hour)); t = t.Add(30 * time.Minute) {
+			model.State(t)
+		}
+	}
+}
+
+// BenchmarkCurveStatePerSlot reads the same slots back from a sampled curve.
+func BenchmarkCurveStatePerSlot(b *testing.B) {
+	curve := NewCapacityCurve(curveModel(16), curveDay, curveDay.Add(24*time.Hour), DefaultCurveResolution)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for t := curveDay; t.Before(curveDay.Add(24 * time.Hour)); t = t.Add(30 * time.Minute) {
+			curve.State(t)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package biomodel
+
+import (
+	"math"
+	"time"
+)
+
+// Chronic sleep debt. Process S only remembers the last night; debt remembers the last week,
+// so five-hour nights keep dragging capacity down even after one good night.
+const (
+	DefaultSleepNeed = 8.0 // Hours of sleep per 24h that keep debt at zero
+
+	debtWindowDays  = 7    // Days of history that count towards debt
+	debtMemory      = 4.0  // Days. Older deficits weigh less: each day counts e^(-k / debtMemory).
+	maxSleepDebt    = 20.0 // Hours. Debt beyond this has no further effect.
+	debtFloorShift  = 0.3  // How far the sleep floor of Process S rises at maximum debt
+	debtPeakLoss    = 0.3  // Capacity ceiling lost at maximum debt
+	highEffortLevel = 7    // Tasks at or above this effort count against the debt budget
+
+	// With debt, the scheduler books at most this much high-effort work, shrinking as debt grows.
+	highEffortBudget = 6 * time.Hour
+	minDebtForBudget = 1.0 // Hours. Below this we don't ration anything.
+)
+
+// sleepNeed returns the daily sleep need, falling back to the default when unset.
+func (b *BioParams) sleepNeed() float64 {
+	if b.SleepNeed <= 0 {
+		return DefaultSleepNeed
+	}
+	return b.SleepNeed
+}
+
+// SleepDebt returns the user's chronic sleep debt (hours) at t: the shortfall against SleepNeed
+// in each of the last days, recent days weighing more. Sleeping in pays it back; the result is
+// never negative. Days before the sleep log begins are not counted.
+//
+//	Debt(t) = max(0, Σk (Need - Slept_k) * e^(-k / debtMemory))
+func (b *BioParams) SleepDebt(t time.Time) float64 {
+	episodes := b.sortedSleep()
+	if len(episodes) == 0 {
+		return 0
+	}
+	// We assume the user was awake (not short of sleep) before the first logged bedtime
+	known := episodes[0].Start.Add(-assumedPriorWake)
+
+	debt := 0.0
+	for k := 0; k < debtWindowDays; k++ {
+		end := t.Add(-time.Duration(k) * 24 * time.Hour)
+		start := end.Add(-24 * time.Hour)
+		if start.Before(known) {
+			break
+		}
+
+		slept := 0.0
+		for _, ep := range episodes {
+			slept += overlapHours(ep.Start, ep.End, start, end)
+		}
+		debt += (b.sleepNeed() - slept) * math.Exp(-float64(k)/debtMemory)
+	}
+	return math.Max(0, debt)
+}
+
+// debtFraction scales debt to 0-1 for the effects below.
+func debtFraction(debt float64) float64 {
+	return math.Min(1, debt/maxSleepDebt)
+}
+
+// debtFloor is how far the lower asymptote of Process S is raised by debt:
+// a short-slept brain never fully clears its adenosine.
+func debtFloor(debt, upper, lower float64) float64 {
+	return (upper - lower) * debtFloorShift * debtFraction(debt)
+}
+
+// PeakCapacity is the highest TotalCapacity the user can reach with their current debt.
+func (s BioState) PeakCapacity() float64 {
+	return 1.0 - debtPeakLoss*debtFraction(s.SleepDebt)
+}
+
+// limit clamps a capacity score between 0 and the debt-limited peak.
+func (s BioState) limit(v float64) float64 {
+	return math.Max(0.0, math.Min(s.PeakCapacity(), v))
+}
+
+// HighEffortBudget returns how many minutes of high-effort work the scheduler books per day for a user
+// with the given debt, or -1 when there is no need to ration.
+func HighEffortBudget(debt float64) int {
+	if debt < minDebtForBudget {
+		return -1
+	}
+	return int(highEffortBudget.Minutes() * (1 - debtFraction(debt)))
+}
+
+// overlapHours returns how many hours [aStart, aEnd) and [bStart, bEnd) share.
+func overlapHours(aStart, aEnd, bStart, bEnd time.Time) float64 {
+	start, end := aStart, aEnd
+	if bStart.After(start) {
+		start = bStart
+	}
+	if bEnd.Before(end) {
+		end = bEnd
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start).Hours()
+}
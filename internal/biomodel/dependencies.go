@@ -0,0 +1,290 @@
+package biomodel
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BlockedFitScore marks tasks left unscheduled because of their dependencies.
+const BlockedFitScore = "Blocked"
+
+// Codes of the ValidationErrors returned for bad task lists.
+const (
+	CodeInvalidTask        = "invalid_task"
+	CodeDuplicateID        = "duplicate_id"
+	CodeUnknownDependency  = "unknown_dependency"
+	CodeDependencyCycle    = "dependency_cycle"
+	CodeDependencyConflict = "dependency_conflict"
+)
+
+// ValidationError is a problem with the submitted tasks, structured so API and MCP clients can
+// point at the tasks involved instead of parsing a message.
+type ValidationError struct {
+	Code    string   `json:"code"`
+	Message string   `json:"message"`
+	Tasks   []string `json:"tasks,omitempty"` // IDs (or names) involved; for a cycle, in order
+}
+
+func (e *ValidationError) Error() string { return e.Message }
+
+// label is how a task is referred to in errors: its ID, or its name if it has none.
+func (t Task) label() string {
+	if t.ID != "" {
+		return t.ID
+	}
+	return t.Name
+}
+
+// ValidateTasks checks every task on its own and then the dependencies between them: IDs are
+// unique, every depends_on names a task in the list, there are no cycles, and no deadline falls
+// before its dependencies can possibly be done. Problems come back as a *ValidationError.
+func ValidateTasks(tasks []Task) error {
+	byID := make(map[string]Task)
+	for _, task := range tasks {
+		if err := task.Validate(); err != nil {
+			return &ValidationError{Code: CodeInvalidTask, Message: err.Error(), Tasks: []string{task.label()}}
+		}
+		if task.ID == "" {
+			continue
+		}
+		if _, dup := byID[task.ID]; dup {
+			return &ValidationError{Code: CodeDuplicateID, Message: fmt.Sprintf("task id %q is used more than once", task.ID), Tasks: []string{task.ID}}
+		}
+		byID[task.ID] = task
+	}
+
+	for _, task := range tasks {
+		for _, dep := range task.DependsOn {
+			if _, ok := byID[dep]; !ok {
+				return &ValidationError{
+					Code:    CodeUnknownDependency,
+					Message: fmt.Sprintf("task %q depends on %q, which is not in the task list", task.label(), dep),
+					Tasks:   []string{task.label(), dep},
+				}
+			}
+		}
+	}
+
+	if cycle := findCycle(tasks, byID); cycle != nil {
+		return &ValidationError{
+			Code:    CodeDependencyCycle,
+			Message: fmt.Sprintf("tasks depend on each other in a cycle: %s", strings.Join(cycle, " -> ")),
+			Tasks:   cycle,
+		}
+	}
+
+	// With the graph sound, catch deadlines no plan could meet
+	finish := earliestFinish{byID: byID, memo: make(map[string]*time.Time)}
+	for _, task := range tasks {
+		if task.Deadline == nil {
+			continue
+		}
+		if ready, from := finish.after(task.DependsOn); ready != nil && ready.Add(time.Duration(task.Duration)*time.Minute).After(*task.Deadline) {
+			return &ValidationError{
+				Code: CodeDependencyConflict,
+				Message: fmt.Sprintf("task %q cannot meet its deadline %s: %q cannot finish before %s", task.label(),
+					task.Deadline.Format(time.RFC3339), from, ready.Format(time.RFC3339)),
+				Tasks: []string{task.label(), from},
+			}
+		}
+	}
+	return nil
+}
+
+// findCycle returns the IDs along a dependency cycle (first ID repeated at the end), or nil.
+// It is a depth-first search that tracks the tasks on the current path.
+func findCycle(tasks []Task, byID map[string]Task) []string {
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	state := make(map[string]int)
+	var path []string
+
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		state[id] = onPath
+		path = append(path, id)
+		for _, dep := range byID[id].DependsOn {
+			switch state[dep] {
+			case onPath:
+				// The cycle is the stretch of the path from dep back round to dep
+				for i, p := range path {
+					if p == dep {
+						return append(append([]string{}, path[i:]...), dep)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = done
+		return nil
+	}
+
+	for _, task := range tasks {
+		if task.ID != "" && state[task.ID] == unvisited {
+			if cycle := visit(task.ID); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// earliestFinish works out the earliest each task can be done, counting only explicit earliest
+// starts and durations (nil if nothing upstream has an earliest start). Each task is worked out
+// once: walking every path instead is exponential on diamond-shaped graphs.
+type earliestFinish struct {
+	byID map[string]Task
+	memo map[string]*time.Time
+}
+
+// after is the earliest all of deps can be done, and which of them is the bottleneck.
+func (f earliestFinish) after(deps []string) (*time.Time, string) {
+	var latest *time.Time
+	bottleneck := ""
+	for _, id := range deps {
+		if end := f.of(id); end != nil && (latest == nil || end.After(*latest)) {
+			latest, bottleneck = end, id
+		}
+	}
+	return latest, bottleneck
+}
+
+// of is the earliest the task with the given ID can be done.
+func (f earliestFinish) of(id string) *time.Time {
+	if end, ok := f.memo[id]; ok {
+		return end
+	}
+	task := f.byID[id]
+	start := task.EarliestStart
+	if ready, _ := f.after(task.DependsOn); ready != nil && (start == nil || ready.After(*start)) {
+		start = ready
+	}
+	var end *time.Time
+	if start != nil {
+		finish := start.Add(time.Duration(task.Duration) * time.Minute)
+		end = &finish
+	}
+	f.memo[id] = end
+	return end
+}
+
+// dependencies tracks where the tasks placed so far ended, and which could not be placed.
+type dependencies struct {
+	finished map[string]time.Time
+	failed   map[string]bool
+}
+
+func newDependencies() dependencies {
+	return dependencies{finished: make(map[string]time.Time), failed: make(map[string]bool)}
+}
+
+// ready reports whether every dependency of the task has been settled, one way or the other.
+func (d dependencies) ready(task Task) bool {
+	for _, dep := range task.DependsOn {
+		if _, ok := d.finished[dep]; !ok && !d.failed[dep] {
+			return false
+		}
+	}
+	return true
+}
+
+// blockedBy returns the first dependency of the task that could not be placed ("" if none).
+func (d dependencies) blockedBy(task Task) string {
+	for _, dep := range task.DependsOn {
+		if d.failed[dep] {
+			return dep
+		}
+	}
+	return ""
+}
+
+// notBefore is when the task's last dependency finishes (zero without dependencies),
+// and which one that is.
+func (d dependencies) notBefore(task Task) (time.Time, string) {
+	var latest time.Time
+	last := ""
+	for _, dep := range task.DependsOn {
+		if end := d.finished[dep]; end.After(latest) {
+			latest, last = end, dep
+		}
+	}
+	return latest, last
+}
+
+// settle records where the task ended up. Tasks without an ID cannot be depended on.
+func (d dependencies) settle(task Task, end time.Time, placed bool) {
+	if task.ID == "" {
+		return
+	}
+	if placed {
+		d.finished[task.ID] = end
+	} else {
+		d.failed[task.ID] = true
+	}
+}
+
+// nextReady removes and returns the first task in the (priority-ordered) list whose dependencies
+// are all settled. Validation rules out cycles, so there always is one.
+func nextReady(pending []Task, deps dependencies) (Task, []Task) {
+	for i, task := range pending {
+		if deps.ready(task) {
+			return task, append(pending[:i:i], pending[i+1:]...)
+		}
+	}
+	return pending[0], pending[1:]
+}
+
+// urgency is what a task is sorted by: its own effort and constraints, raised to those of the
+// tasks that (directly or not) depend on it.
+type urgency struct {
+	effort      int
+	constrained bool
+}
+
+type urgencies map[string]urgency
+
+// inheritUrgency computes the urgency of every task with an ID. Validation rules out cycles.
+func inheritUrgency(tasks []Task) urgencies {
+	byID := make(map[string]Task)
+	for _, task := range tasks {
+		if task.ID != "" {
+			byID[task.ID] = task
+		}
+	}
+	u := make(urgencies)
+	var raise func(id string, by urgency)
+	raise = func(id string, by urgency) {
+		current, seen := u[id]
+		if seen && current.effort >= by.effort && (current.constrained || !by.constrained) {
+			return
+		}
+		current.effort = max(current.effort, by.effort)
+		current.constrained = current.constrained || by.constrained
+		u[id] = current
+		for _, dep := range byID[id].DependsOn {
+			raise(dep, current)
+		}
+	}
+	for _, task := range tasks {
+		if task.ID != "" {
+			raise(task.ID, urgency{effort: task.Effort, constrained: task.constrained()})
+		}
+	}
+	return u
+}
+
+// of returns the task's urgency (its own, if it has no ID).
+func (u urgencies) of(task Task) urgency {
+	if inherited, ok := u[task.ID]; ok && task.ID != "" {
+		return inherited
+	}
+	return urgency{effort: task.Effort, constrained: task.constrained()}
+}
@@ -0,0 +1,128 @@
+package biomodel
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateTasksCodes(t *testing.T) {
+	start := time.Date(2026, 3, 10, 8, 0, 0, 0, time.UTC)
+	deadline := start.Add(90 * time.Minute)
+
+	tests := []struct {
+		name  string
+		tasks []Task
+		code  string // "" for a valid list
+		ids   []string
+	}{
+		{"valid", []Task{
+			{ID: "a", Name: "a", Duration: 30, Effort: 3},
+			{ID: "b", Name: "b", Duration: 30, Effort: 3, DependsOn: []string{"a"}},
+		}, "", nil},
+		{"invalid task", []Task{{Name: "zero", Duration: 0, Effort: 3}}, CodeInvalidTask, []string{"zero"}},
+		{"self dependency", []Task{{ID: "a", Name: "a", Duration: 30, Effort: 3, DependsOn: []string{"a"}}}, CodeInvalidTask, []string{"a"}},
+		{"duplicate id", []Task{
+			{ID: "a", Name: "first", Duration: 30, Effort: 3},
+			{ID: "a", Name: "second", Duration: 30, Effort: 3},
+		}, CodeDuplicateID, []string{"a"}},
+		{"unknown dependency", []Task{{ID: "a", Name: "a", Duration: 30, Effort: 3, DependsOn: []string{"ghost"}}},
+			CodeUnknownDependency, []string{"a", "ghost"}},
+		{"cycle", []Task{
+			{ID: "a", Name: "a", Duration: 30, Effort: 3, DependsOn: []string{"c"}},
+			{ID: "b", Name: "b", Duration: 30, Effort: 3, DependsOn: []string{"a"}},
+			{ID: "c", Name: "c", Duration: 30, Effort: 3, DependsOn: []string{"b"}},
+		}, CodeDependencyCycle, []string{"a", "c", "b", "a"}},
+		{"deadline before a dependency can finish", []Task{
+			{ID: "build", Name: "build", Duration: 60, Effort: 3, EarliestStart: &start},
+			{ID: "test", Name: "test", Duration: 30, Effort: 3, DependsOn: []string{"build"}},
+			{ID: "ship", Name: "ship", Duration: 30, Effort: 3, DependsOn: []string{"test"}, Deadline: &deadline},
+		}, CodeDependencyConflict, []string{"ship", "test"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTasks(tt.tasks)
+			if tt.code == "" {
+				if err != nil {
+					t.Fatalf("want a valid list, got %v", err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("want a *ValidationError with code %q, got %v", tt.code, err)
+			}
+			if verr.Code != tt.code || !slices.Equal(verr.Tasks, tt.ids) {
+				t.Errorf("got code %q for %v, want %q for %v (%s)", verr.Code, verr.Tasks, tt.code, tt.ids, verr.Message)
+			}
+		})
+	}
+}
+
+func TestInheritUrgencyRaisesGroundwork(t *testing.T) {
+	tasks := []Task{
+		{ID: "notes", Name: "notes", Duration: 30, Effort: 2},
+		{ID: "proof", Name: "proof", Duration: 60, Effort: 9, DependsOn: []string{"notes"}},
+		{Name: "email", Duration: 30, Effort: 4},
+	}
+	urgency := inheritUrgency(tasks)
+	if got := urgency.of(tasks[0]).effort; got != 9 {
+		t.Errorf("notes feed an effort-9 task, want urgency 9, got %d", got)
+	}
+	if got := urgency.of(tasks[2]).effort; got != 4 {
+		t.Errorf("email has no dependents, want its own effort 4, got %d", got)
+	}
+}
+
+func TestAllocateBlocksDependentsOfUnscheduledTasks(t *testing.T) {
+	start := time.Date(2026, 3, 10, 8, 0, 0, 0, time.UTC)
+	late := start.Add(24 * time.Hour)
+	tasks := []Task{
+		{ID: "build", Name: "build", Duration: 60, Effort: 5, EarliestStart: &late},
+		{ID: "deploy", Name: "deploy", Duration: 30, Effort: 5, DependsOn: []string{"build"}},
+	}
+
+	schedule, _ := allocate(tasks, flatSlots(start, 0.8, 0.8, 0.8, 0.8), -1, ScheduleOptions{})
+	for _, item := range schedule {
+		if item.TaskID == "deploy" {
+			if item.FitScore != BlockedFitScore || !strings.Contains(item.Reason, `depends on "build"`) {
+				t.Errorf("deploy: got fit %q, reason %q; want %q naming build", item.FitScore, item.Reason, BlockedFitScore)
+			}
+			return
+		}
+	}
+	t.Fatalf("deploy is missing from %+v", schedule)
+}
+
+func TestValidateTasksDeepDiamondIsFast(t *testing.T) {
+	// Layers of two tasks, each depending on both tasks of the layer before: 2^layers paths
+	start := time.Date(2026, 3, 10, 8, 0, 0, 0, time.UTC)
+	const layers = 40
+	tasks := []Task{{ID: "root", Name: "root", Duration: 10, Effort: 3, EarliestStart: &start}}
+	prev := []string{"root"}
+	for l := 0; l < layers; l++ {
+		var layer []string
+		for k := 0; k < 2; k++ {
+			id := fmt.Sprintf("l%d-%d", l, k)
+			tasks = append(tasks, Task{ID: id, Name: id, Duration: 10, Effort: 3, DependsOn: prev})
+			layer = append(layer, id)
+		}
+		prev = layer
+	}
+	deadline := start.Add(time.Hour)
+	tasks = append(tasks, Task{ID: "ship", Name: "ship", Duration: 10, Effort: 3, DependsOn: prev, Deadline: &deadline})
+
+	begin := time.Now()
+	err := ValidateTasks(tasks)
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Errorf("validating %d layers took %v", layers, elapsed)
+	}
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Code != CodeDependencyConflict {
+		t.Errorf("the chain needs %d minutes before a one-hour deadline, want %q, got %v", 10*(layers+1), CodeDependencyConflict, err)
+	}
+}
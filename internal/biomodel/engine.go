@@ -1,6 +1,7 @@
 package biomodel
 
 import (
+	"fmt"
 	"math"
 	"time"
 )
@@ -8,42 +9,129 @@ import (
 // BioParams represents the biological constants unique to a specific user.
 // These would eventually be loaded from a DB, but for now, they are the model's configuration.
 type BioParams struct {
-	WakeTime      time.Time // The reference point for Homeostatic pressure (Process S)
+	WakeTime      time.Time // The reference point for Homeostatic pressure (Process S). Ignored when Sleep is set.
 	ChronotypeLag float64   // Shift in hours for Circadian Rhythm (Process C). e.g., 0 for normal, +2 for Night Owl.
 	FatigueRate   float64   // Sensitivity to adenosine. Lower = faster fatigue. Typical range 14.0 - 18.0.
+
+	// IANA zone the user lives in, e.g. "Europe/Berlin". Circadian phase is read on this clock.
+	// Empty keeps the location carried by targetTime.
+	TimeZone string
+
+	// Sleep-phase recovery. Zero values fall back to the Default* constants.
+	Sleep          SleepLog // Known (and planned) sleep. S decays during these and rises in between.
+	RecoveryRate   float64  // Time constant (hours) of S decaying during sleep. Typical ~4.2.
+	UpperAsymptote float64  // Ceiling S approaches while awake.
+	LowerAsymptote float64  // Floor S approaches while asleep.
+	SleepNeed      float64  // Hours of sleep per day before debt builds up. Typical 7 - 9.
+
+	// Travel. When set, Process C follows the body clock re-entraining to each new zone
+	// instead of the location carried by targetTime.
+	Itinerary Itinerary
+
+	// Shape of Process C. The zero value is the classic sinusoid.
+	Circadian CircadianProfile
+
+	// Light exposure. When set, Process C comes from a Kronauer limit-cycle oscillator whose
+	// phase advances or delays with light, instead of the fixed sinusoid.
+	Light LightLog
+
+	// Stimulants. Caffeine temporarily masks (but does not remove) sleep pressure.
+	Caffeine         CaffeineLog
+	CaffeineHalfLife float64 // Hours. Zero falls back to DefaultCaffeineHalfLife. See ValidateCaffeineHalfLife.
+
+	// Ultradian (~90 min) focus cycles on top of the daily rhythm. Off unless Amplitude is set.
+	Ultradian Ultradian
+
+	// Sleep inertia. Zero falls back to DefaultInertiaDecay.
+	InertiaDecay float64 // Time constant (hours) of post-wake grogginess fading. Typical 0.3 - 0.7.
 }
 
 // BioState holds the calculated result of the model at a specific point in time.
 type BioState struct {
 	ProcessS      float64 // Sleep Pressure (0.0 to 1.0)
 	ProcessC      float64 // Circadian Arousal (0.0 to 1.0)
+	ProcessW      float64 // Sleep Inertia (0.0 to 1.0, 1.0 = just woke up)
+	Ultradian     float64 // BRAC contribution to capacity (-amplitude to +amplitude, 0 when off)
+	Caffeine      float64 // Caffeine effect (0.0 to 1.0). Already folded into ProcessS.
+	SleepDebt     float64 // Chronic sleep debt in hours. Lowers recovery and the capacity ceiling.
 	TotalCapacity float64 // The final available "Brain Battery" (0.0 to 1.0)
+	Asleep        bool    // True when targetTime falls inside a sleep episode
 }
 
-// CalculateState computes the biological capacity for a specific target time.
-// It implements the Borbély Two-Process Model.
+// CalculateState computes the biological capacity for a specific target time
+// using the default AverageModel. Use a CapacityModel to pick a different combination.
 func (b *BioParams) CalculateState(targetTime time.Time) BioState {
+	return AverageModel{Params: *b}.State(targetTime)
+}
+
+// components computes the individual processes for a specific target time.
+// It implements the Borbély Two-Process Model, plus the sleep inertia term (Process W)
+// from Åkerstedt's Three-Process Model. TotalCapacity is left for a CapacityModel to fill in.
+func (b *BioParams) components(targetTime time.Time) BioState {
 
 	// --- Process S: The Homeostat (Sleep Pressure) ---
-	// Formula: S(t) = 1 - e^(-t / tau)
-	// As 'hoursAwake' increases, the result approaches 1.0 (maximum sleep pressure).
-	hoursAwake := targetTime.Sub(b.WakeTime).Hours()
-	if hoursAwake < 0 {
-		hoursAwake = 0 // Handle edge case if checking time before wake
+	// S rises toward the upper asymptote while awake and decays toward the lower one while asleep.
+	// See sleepPressure for the piecewise exponentials.
+	sleepPressure, asleep := b.sleepPressure(targetTime)
+	debt := b.SleepDebt(targetTime)
+
+	// --- Caffeine: masks part of the pressure while it is in the body ---
+	caffeine := 0.0
+	if !asleep && len(b.Caffeine) > 0 {
+		caffeine = b.caffeineEffect(targetTime)
 	}
 
-	// We calculate "Freshness" as the inverse of pressure.
+	// We calculate "Freshness" as the inverse of pressure, normalized between the asymptotes.
 	// 1.0 = Fresh, 0.0 = Exhausted.
-	sleepPressure := 1.0 - math.Exp(-hoursAwake/b.FatigueRate)
-	processS_Freshness := 1.0 - sleepPressure
+	upper, lower := b.asymptotes()
+	perceived := lower + (sleepPressure-lower)*(1.0-caffeineMaxMask*caffeine)
+	processS_Freshness := (upper - perceived) / (upper - lower)
 
 	// --- Process C: The Circadian Pacemaker ---
+	processC_Normalized := b.circadian(targetTime)
+
+	// --- Process W: Sleep Inertia ---
+	// Grogginess right after waking, fading exponentially. Zero while asleep.
+	processW := 0.0
+	if !asleep {
+		processW = b.sleepInertia(targetTime)
+	}
+
+	// --- Ultradian rhythm: ~90 minute focus waves while awake ---
+	ultradian := 0.0
+	if !asleep {
+		ultradian = b.ultradian(targetTime)
+	}
+
+	return BioState{
+		ProcessS:  processS_Freshness,
+		ProcessC:  processC_Normalized,
+		ProcessW:  processW,
+		Ultradian: ultradian,
+		Caffeine:  caffeine,
+		SleepDebt: debt,
+		Asleep:    asleep,
+	}
+}
+
+// circadian computes Process C, the circadian arousal (0.0 to 1.0) at targetTime.
+func (b *BioParams) circadian(targetTime time.Time) float64 {
+	// With a light history we run the light-driven oscillator instead of the fixed sinusoid
+	if len(b.Light) > 0 {
+		return b.lightOscillator(targetTime)
+	}
+
+	// Calculate hours since midnight for the target day.
+	timeOfDay := b.bodyClockHour(targetTime)
+
+	// A user-specific shape (e.g. with a post-lunch dip), shifted by chronotype like the sinusoid
+	if b.Circadian.custom() {
+		return b.Circadian.at(timeOfDay - b.ChronotypeLag)
+	}
+
 	// Formula: Sinusoidal wave representing the SCN drive.
 	// We map the 24h cycle to 2*Pi radians.
 
-	// Calculate hours since midnight for the target day
-	timeOfDay := float64(targetTime.Hour()) + float64(targetTime.Minute())/60.0
-
 	// Standard circadian peak is usually around late afternoon.
 	// We use ChronotypeLag to shift the wave left or right.
 	// The "- 6" shifts the standard sine wave so it starts rising in the morning.
@@ -51,30 +139,75 @@ func (b *BioParams) CalculateState(targetTime time.Time) BioState {
 
 	// Normalize Process C from [-1, 1] to [0, 1]
 	processC_Normalized := (processC_Raw + 1.0) / 2.0
+	return processC_Normalized
+}
 
-	// --- Integration: The TardiGo Algorithm ---
-	// We combine the Circadian drive with the Homeostatic freshness.
-	// Heuristic: Capacity is the average of your freshness and your circadian drive.
-	totalCapacity := (processS_Freshness + processC_Normalized) / 2.0
-
-	return BioState{
-		ProcessS:      processS_Freshness,
-		ProcessC:      processC_Normalized,
-		TotalCapacity: math.Max(0.0, math.Min(1.0, totalCapacity)), // Clamp between 0-1
+// bodyClockHour returns the hours since midnight on the clock the circadian pacemaker follows.
+// At home that is the user's own zone. After travel the body clock lags the wall clock, so we read
+// the time in the zone it is entrained to.
+func (b *BioParams) bodyClockHour(targetTime time.Time) float64 {
+	clock := b.local(targetTime)
+	if len(b.Itinerary) > 0 {
+		offset := time.Duration(b.Itinerary.bodyOffset(targetTime) * float64(time.Hour))
+		clock = targetTime.UTC().Add(offset)
 	}
+	return float64(clock.Hour()) + float64(clock.Minute())/60.0
 }
 
 // Task represents a unit of work to be scheduled.
 type Task struct {
+	ID       string `json:"id,omitempty"` // Only needed to be named in another task's depends_on
 	Name     string `json:"name"`
 	Duration int    `json:"duration_minutes"` // e.g., 60
 	Effort   int    `json:"effort_level"`     // 1-10 (10 = Hardest)
+
+	// Hard timing constraints, all optional. A task that cannot meet them is reported as
+	// Infeasible with the reason, rather than booked somewhere else.
+	EarliestStart *time.Time  `json:"earliest_start,omitempty"` // Not before, e.g. when the build lands
+	Deadline      *time.Time  `json:"deadline,omitempty"`       // Finished by
+	Windows       []TimeBlock `json:"windows,omitempty"`        // Run entirely inside one of these
+
+	// IDs of tasks that must be finished before this one starts.
+	DependsOn []string `json:"depends_on,omitempty"`
+
+	// Splittable tasks may be booked as several focus blocks of MinChunk to MaxChunk minutes
+	// (30 and 90 unless set), in different high-capacity windows.
+	Splittable bool `json:"splittable,omitempty"`
+	MinChunk   int  `json:"min_chunk_minutes,omitempty"`
+	MaxChunk   int  `json:"max_chunk_minutes,omitempty"`
+}
+
+// Validate checks the task has a positive duration, an effort on the 1-10 scale, sensible chunk
+// limits and well-formed timing constraints.
+func (t Task) Validate() error {
+	if t.Duration <= 0 {
+		return fmt.Errorf("task %q: duration_minutes must be positive, got %d", t.Name, t.Duration)
+	}
+	if t.Effort < 1 || t.Effort > 10 {
+		return fmt.Errorf("task %q: effort_level must be 1-10, got %d", t.Name, t.Effort)
+	}
+	for _, dep := range t.DependsOn {
+		if dep == "" || dep == t.ID {
+			return fmt.Errorf("task %q: depends_on cannot contain %q", t.Name, dep)
+		}
+	}
+	if err := t.validateSplit(); err != nil {
+		return err
+	}
+	return t.validateConstraints()
 }
 
 // ScheduleItem is a task assigned to a specific time slot.
 type ScheduleItem struct {
-	StartTime    string  `json:"start_time"`
-	TaskName     string  `json:"task_name"`
-	PredictedCap float64 `json:"predicted_capacity"`
-	FitScore     string  `json:"fit_score"` // "Perfect", "Good", "Bad"
+	StartTime    string        `json:"start_time"` // RFC3339 in the user's zone, or "UNSCHEDULED"
+	TaskID       string        `json:"task_id,omitempty"`
+	TaskName     string        `json:"task_name"`
+	Duration     int           `json:"duration_minutes,omitempty"`
+	PredictedCap float64       `json:"predicted_capacity"`
+	Interval     *CapacityBand `json:"interval,omitempty"`    // Mean, p10 and p90 when planned with an Ensemble
+	Performance  *Performance  `json:"performance,omitempty"` // KSS, PVT lapses and error rate at PredictedCap
+	FitScore     string        `json:"fit_score"`             // "Perfect", "Good", "Bad"
+	Part         int           `json:"part,omitempty"`        // Which focus block of a split task, from 1
+	Parts        int           `json:"parts,omitempty"`       // How many blocks the task was split into
+	Reason       string        `json:"reason,omitempty"`      // Why an UNSCHEDULED task could not be placed
 }
@@ -0,0 +1,99 @@
+package biomodel
+
+import (
+	"fmt"
+	"time"
+)
+
+// EventFitScore marks fixed events in a schedule.
+const EventFitScore = "Fixed"
+
+// Event is fixed time the scheduler plans around: a meeting, lunch, a school run.
+// Its slots are booked before any task is placed, and an event with an effort drains capacity
+// like a task would, so a morning of meetings leaves less for the afternoon.
+type Event struct {
+	Name   string    `json:"name"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Effort int       `json:"effort_level"` // 0-10. 0 blocks the time without tiring the user (e.g. lunch).
+}
+
+// Validate checks the event ends after it starts and its effort is on the 0-10 scale.
+func (e Event) Validate() error {
+	if !e.End.After(e.Start) {
+		return fmt.Errorf("event %q starting %s does not end after it starts", e.Name, e.Start.Format(time.RFC3339))
+	}
+	if e.Effort < 0 || e.Effort > 10 {
+		return fmt.Errorf("event %q: effort_level must be 0-10, got %d", e.Name, e.Effort)
+	}
+	return nil
+}
+
+// booking is the event's load on the workload.
+func (e Event) booking() booking {
+	return booking{
+		Start: e.Start,
+		End:   e.End,
+		Load:  float64(e.Effort) / 10.0 * e.End.Sub(e.Start).Hours(),
+	}
+}
+
+// overlaps reports whether the event takes up any of the slot starting at t.
+func (e Event) overlaps(t time.Time, slot time.Duration) bool {
+	return e.Start.Before(t.Add(slot)) && t.Before(e.End)
+}
+
+// blockEvents books every slot an event overlaps, so neither tasks nor naps go there.
+func blockEvents(slots []Slot, events []Event, opts ScheduleOptions) {
+	for i := range slots {
+		for _, e := range events {
+			if e.overlaps(slots[i].Time, opts.slotLength()) {
+				slots[i].IsBooked = true
+				break
+			}
+		}
+	}
+}
+
+// placeEvents adds the events that fall inside the slots to the workload and the schedule.
+// Their capacity is the average over the slots they overlap.
+func placeEvents(slots []Slot, events []Event, opts ScheduleOptions, load *workload) []placement {
+	var items []placement
+	for _, e := range events {
+		var overlapped []Slot
+		var taken []int
+		for k, s := range slots {
+			if e.overlaps(s.Time, opts.slotLength()) {
+				overlapped = append(overlapped, s)
+				taken = append(taken, k)
+			}
+		}
+		if len(overlapped) == 0 {
+			continue // Outside the horizon
+		}
+
+		weights := make([]float64, len(overlapped))
+		for i := range weights {
+			weights[i] = 1 / float64(len(overlapped))
+		}
+		capacity := averageCapacity(overlapped, weights)
+		performance := PerformanceAt(capacity)
+		items = append(items, placement{
+			ScheduleItem: ScheduleItem{
+				StartTime:    e.Start.In(overlapped[0].Time.Location()).Format(time.RFC3339),
+				TaskName:     e.Name,
+				Duration:     int(e.End.Sub(e.Start).Minutes()),
+				PredictedCap: capacity,
+				Interval:     averageBand(overlapped, weights),
+				Performance:  &performance,
+				FitScore:     EventFitScore,
+			},
+			slots:   taken,
+			weights: weights,
+		})
+		if e.Effort > 0 {
+			*load = append(*load, e.booking())
+		}
+	}
+	return items
+}
@@ -0,0 +1,194 @@
+package biomodel
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Observation is a measured alertness level on the same 0-1 scale as TotalCapacity.
+// Weight expresses how much we trust the source (self-reports > HRV).
+type Observation struct {
+	Time      time.Time `json:"time"`
+	Alertness float64   `json:"alertness"`
+	Weight    float64   `json:"weight"`
+}
+
+// HRVSample is a single heart-rate-variability reading (RMSSD, ms).
+type HRVSample struct {
+	Time time.Time
+	HRV  float64
+}
+
+// FitResult is the best-fitting parameter set and how well it explains the observations.
+type FitResult struct {
+	FatigueRate   float64 `json:"fatigue_rate"`
+	ChronotypeLag float64 `json:"chronotype_lag"`
+	Weights       Weights `json:"weights"`
+	RMSE          float64 `json:"rmse"`      // Root mean squared error on the 0-1 scale
+	RSquared      float64 `json:"r_squared"` // Share of variance explained (1.0 = perfect)
+	Samples       int     `json:"samples"`
+}
+
+// Search grid and data requirements for FitParams.
+const (
+	MinFitObservations = 12
+
+	fitFatigueMin  = 10.0
+	fitFatigueMax  = 24.0
+	fitFatigueStep = 0.5
+	fitLagMin      = -4.0
+	fitLagMax      = 4.0
+	fitLagStep     = 0.25
+
+	kssWeight = 1.0 // Self-reports are the ground truth we fit to
+	hrvWeight = 0.3 // HRV only loosely tracks alertness
+)
+
+// ObservationFromKSS converts a Karolinska Sleepiness Scale self-report (1 = extremely alert,
+// 9 = fighting sleep) into an observation.
+func ObservationFromKSS(t time.Time, kss int) (Observation, error) {
+	if kss < 1 || kss > 9 {
+		return Observation{}, fmt.Errorf("KSS must be 1-9, got %d", kss)
+	}
+	return Observation{Time: t, Alertness: float64(9-kss) / 8.0, Weight: kssWeight}, nil
+}
+
+// ObservationsFromHRV converts HRV readings into low-weight observations. HRV is only meaningful
+// relative to the person's own baseline, so readings are z-scored and mapped around 0.5.
+func ObservationsFromHRV(samples []HRVSample) []Observation {
+	if len(samples) < 2 {
+		return nil
+	}
+
+	mean, sd := 0.0, 0.0
+	for _, s := range samples {
+		mean += s.HRV
+	}
+	mean /= float64(len(samples))
+	for _, s := range samples {
+		sd += (s.HRV - mean) * (s.HRV - mean)
+	}
+	sd = math.Sqrt(sd / float64(len(samples)))
+	if sd == 0 {
+		return nil // A flat signal tells us nothing
+	}
+
+	obs := make([]Observation, 0, len(samples))
+	for _, s := range samples {
+		z := (s.HRV - mean) / sd
+		obs = append(obs, Observation{Time: s.Time, Alertness: clamp01(0.5 + 0.15*z), Weight: hrvWeight})
+	}
+	return obs
+}
+
+// FitParams estimates FatigueRate, ChronotypeLag and the WeightedModel weights from observations,
+// keeping everything else (notably the sleep log) from base.
+//
+// It grid-searches the two nonlinear parameters and, for each grid point, solves the weights in
+// closed form by weighted least squares on
+//
+//	alertness = a*S + (1-a)*C - w*W,   0 <= a <= 1, w >= 0
+func FitParams(base BioParams, obs []Observation) (FitResult, error) {
+	if len(obs) < MinFitObservations {
+		return FitResult{}, fmt.Errorf("need at least %d observations to fit, got %d", MinFitObservations, len(obs))
+	}
+
+	var best FitResult
+	bestSSE := math.Inf(1)
+	s := make([]float64, len(obs))
+	c := make([]float64, len(obs))
+	w := make([]float64, len(obs))
+
+	for fatigue := fitFatigueMin; fatigue <= fitFatigueMax; fatigue += fitFatigueStep {
+		candidate := base
+		candidate.FatigueRate = fatigue
+		for i, o := range obs {
+			state := candidate.components(o.Time)
+			s[i], w[i] = state.ProcessS, state.ProcessW
+		}
+
+		for lag := fitLagMin; lag <= fitLagMax; lag += fitLagStep {
+			candidate.ChronotypeLag = lag
+			for i, o := range obs {
+				c[i] = candidate.circadian(o.Time)
+			}
+
+			a, inertia, sse := solveWeights(obs, s, c, w)
+			if sse < bestSSE {
+				bestSSE = sse
+				best.FatigueRate = fatigue
+				best.ChronotypeLag = lag
+				best.Weights = Weights{Homeostatic: a, Circadian: 1 - a, Inertia: inertia}
+			}
+		}
+	}
+
+	// Fit quality
+	totalWeight, mean := 0.0, 0.0
+	for _, o := range obs {
+		totalWeight += o.Weight
+		mean += o.Weight * o.Alertness
+	}
+	if totalWeight <= 0 {
+		return FitResult{}, fmt.Errorf("observations carry no weight")
+	}
+	mean /= totalWeight
+
+	sst := 0.0
+	for _, o := range obs {
+		sst += o.Weight * (o.Alertness - mean) * (o.Alertness - mean)
+	}
+
+	best.RMSE = math.Sqrt(bestSSE / totalWeight)
+	if sst > 0 {
+		best.RSquared = 1 - bestSSE/sst
+	}
+	best.Samples = len(obs)
+	return best, nil
+}
+
+// solveWeights fits y - C = a*(S - C) - w*W by weighted least squares, clamping a to [0, 1] and w
+// to >= 0 (re-solving the remaining coefficient when a bound is hit). It returns a, w and the SSE.
+func solveWeights(obs []Observation, s, c, w []float64) (float64, float64, float64) {
+	// Normal equations for x1 = S - C, x2 = -W, target y = alertness - C
+	var s11, s12, s22, b1, b2 float64
+	for i, o := range obs {
+		x1, x2, y := s[i]-c[i], -w[i], o.Alertness-c[i]
+		s11 += o.Weight * x1 * x1
+		s12 += o.Weight * x1 * x2
+		s22 += o.Weight * x2 * x2
+		b1 += o.Weight * x1 * y
+		b2 += o.Weight * x2 * y
+	}
+
+	a, inertia := 0.5, 0.0
+	det := s11*s22 - s12*s12
+	if math.Abs(det) > 1e-12 {
+		a = (b1*s22 - b2*s12) / det
+		inertia = (s11*b2 - s12*b1) / det
+	} else if s11 > 0 {
+		a = b1 / s11 // No inertia in the data (e.g. nobody reported right after waking)
+	}
+
+	// Project back into the feasible region one coefficient at a time
+	if a < 0 || a > 1 {
+		a = math.Max(0, math.Min(1, a))
+		if s22 > 0 {
+			inertia = (b2 - a*s12) / s22
+		}
+	}
+	if inertia < 0 {
+		inertia = 0
+		if s11 > 0 {
+			a = math.Max(0, math.Min(1, b1/s11))
+		}
+	}
+
+	sse := 0.0
+	for i, o := range obs {
+		pred := a*s[i] + (1-a)*c[i] - inertia*w[i]
+		sse += o.Weight * (o.Alertness - pred) * (o.Alertness - pred)
+	}
+	return a, inertia, sse
+}
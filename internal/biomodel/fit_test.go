@@ -0,0 +1,123 @@
+package biomodel
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fitWeek is the week the fit tests observe, slept on the default routine.
+var fitWeek = time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+
+// weightedObservations are what a user with these parameters and weights would report every two
+// waking hours during fitWeek.
+func weightedObservations(t *testing.T, params BioParams, weights Weights) []Observation {
+	t.Helper()
+	model, err := NewCapacityModel(ModelWeighted, params, weights)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var obs []Observation
+	for at := fitWeek; at.Before(fitWeek.Add(7 * 24 * time.Hour)); at = at.Add(time.Hour) {
+		// Awake 07:00-23:00; 07:00 itself is in, so there is grogginess to fit
+		if h := at.Hour(); h < 7 || h == 23 || h%2 != 0 && h != 7 {
+			continue
+		}
+		obs = append(obs, Observation{Time: at, Alertness: model.State(at).TotalCapacity, Weight: kssWeight})
+	}
+	return obs
+}
+
+func TestFitParamsRecoversKnownParameters(t *testing.T) {
+	truth := FitResult{FatigueRate: 14, ChronotypeLag: 1.5, Weights: Weights{Homeostatic: 0.6, Circadian: 0.4, Inertia: 0.3}}
+	sleep, _ := DefaultRoutine.Nights(fitWeek, 7)
+	params := BioParams{FatigueRate: truth.FatigueRate, ChronotypeLag: truth.ChronotypeLag, Sleep: sleep}
+	obs := weightedObservations(t, params, truth.Weights)
+
+	// Fitting starts from the defaults and only keeps the sleep log
+	got, err := FitParams(BioParams{Sleep: sleep}, obs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		name      string
+		got, want float64
+		tolerance float64
+	}{
+		{"fatigue rate", got.FatigueRate, truth.FatigueRate, fitFatigueStep},
+		{"chronotype lag", got.ChronotypeLag, truth.ChronotypeLag, fitLagStep},
+		{"homeostatic weight", got.Weights.Homeostatic, truth.Weights.Homeostatic, 0.05},
+		{"circadian weight", got.Weights.Circadian, truth.Weights.Circadian, 0.05},
+		{"inertia weight", got.Weights.Inertia, truth.Weights.Inertia, 0.05},
+	} {
+		if math.Abs(c.got-c.want) > c.tolerance {
+			t.Errorf("%s: got %.3f, want %.3f ± %.2f", c.name, c.got, c.want, c.tolerance)
+		}
+	}
+	if got.RMSE > 0.01 || got.RSquared < 0.99 || got.Samples != len(obs) {
+		t.Errorf("noise-free data fitted with RMSE %.4f, R² %.4f over %d samples; want a near-perfect fit over %d",
+			got.RMSE, got.RSquared, got.Samples, len(obs))
+	}
+}
+
+func TestFitParamsNeedsEnoughObservations(t *testing.T) {
+	sleep, _ := DefaultRoutine.Nights(fitWeek, 7)
+	obs := weightedObservations(t, BioParams{Sleep: sleep}, DefaultWeights)
+	if _, err := FitParams(BioParams{}, obs[:MinFitObservations-1]); err == nil || !strings.Contains(err.Error(), "at least") {
+		t.Errorf("%d observations: got error %v, want one asking for at least %d", MinFitObservations-1, err, MinFitObservations)
+	}
+
+	weightless := append([]Observation(nil), obs[:MinFitObservations]...)
+	for i := range weightless {
+		weightless[i].Weight = 0
+	}
+	if _, err := FitParams(BioParams{}, weightless); err == nil {
+		t.Error("observations without weight were fitted")
+	}
+}
+
+func TestSolveWeightsProjectsOntoBounds(t *testing.T) {
+	// Hand-made components, so the unconstrained solution can be put outside the bounds
+	s := []float64{0.9, 0.8, 0.7, 0.6, 0.5, 0.4, 0.3, 0.2}
+	c := []float64{0.2, 0.5, 0.9, 0.4, 0.7, 0.1, 0.6, 0.3}
+	w := []float64{0.4, 0.0, 0.2, 0.0, 0.1, 0.0, 0.3, 0.0}
+	generate := func(a, inertia float64) []Observation {
+		obs := make([]Observation, len(s))
+		for i := range s {
+			obs[i] = Observation{Alertness: a*s[i] + (1-a)*c[i] - inertia*w[i], Weight: 1}
+		}
+		return obs
+	}
+
+	tests := []struct {
+		name             string
+		a, inertia       float64 // Used to generate the data
+		wantA, wantInert float64 // What is fitted
+		exact            bool    // Feasible data is fitted without error
+	}{
+		{"inside the bounds", 0.7, 0.2, 0.7, 0.2, true},
+		{"homeostatic weight above 1", 1.5, 0.2, 1, -1, false},
+		{"homeostatic weight below 0", -0.5, 0.2, 0, -1, false},
+		{"negative inertia", 0.7, -0.3, -1, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, inertia, sse := solveWeights(generate(tt.a, tt.inertia), s, c, w)
+			if a < 0 || a > 1 || inertia < 0 {
+				t.Fatalf("got a = %.3f, inertia = %.3f, outside 0 <= a <= 1, inertia >= 0", a, inertia)
+			}
+			if tt.wantA >= 0 && math.Abs(a-tt.wantA) > 1e-9 {
+				t.Errorf("got a = %.3f, want %.3f", a, tt.wantA)
+			}
+			if tt.wantInert >= 0 && math.Abs(inertia-tt.wantInert) > 1e-9 {
+				t.Errorf("got inertia = %.3f, want %.3f", inertia, tt.wantInert)
+			}
+			if exact := sse < 1e-12; exact != tt.exact {
+				t.Errorf("got SSE %.2g, want an exact fit %v", sse, tt.exact)
+			}
+		})
+	}
+}
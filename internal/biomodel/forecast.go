@@ -0,0 +1,49 @@
+package biomodel
+
+import "time"
+
+// ForecastPoint is the model output at one step of a multi-day forecast.
+type ForecastPoint struct {
+	Time      time.Time     `json:"time"`
+	Capacity  float64       `json:"capacity"`
+	Freshness float64       `json:"freshness"`
+	Circadian float64       `json:"circadian"`
+	Inertia   float64       `json:"inertia"`
+	Ultradian float64       `json:"ultradian,omitempty"`
+	Band      *CapacityBand `json:"band,omitempty"` // Mean, p10 and p90 when forecasting with an Ensemble
+	Asleep    bool          `json:"asleep"`
+}
+
+// Forecast evaluates the model every step from 'from' up to (but not including) 'to'.
+// Combined with a SleepLog that contains planned nights, this covers several days at once.
+// An Ensemble adds the Monte Carlo band to every point. Times are in the user's zone.
+// The points are read off a CapacityCurve, which a cached curve passed in as the model provides for free.
+func Forecast(model CapacityModel, from, to time.Time, step time.Duration) []ForecastPoint {
+	if step <= 0 {
+		step = time.Hour
+	}
+	from = model.Biology().local(from)
+	if !to.After(from) {
+		return nil
+	}
+	curve := curveFor(model, from, to.Add(-1), step)
+
+	var points []ForecastPoint
+	for t := from; t.Before(to); t = t.Add(step) {
+		state := curve.State(t)
+		point := ForecastPoint{
+			Time:      t,
+			Capacity:  state.TotalCapacity,
+			Freshness: state.ProcessS,
+			Circadian: state.ProcessC,
+			Inertia:   state.ProcessW,
+			Ultradian: state.Ultradian,
+			Asleep:    state.Asleep,
+		}
+		if band, ok := curve.Band(t); ok {
+			point.Band = &band
+		}
+		points = append(points, point)
+	}
+	return points
+}
@@ -0,0 +1,120 @@
+package biomodel
+
+import (
+	"fmt"
+	"time"
+)
+
+// Planning horizon. By default OptimizeSchedule books the next 12 hours; longer horizons let
+// tasks that do not fit today spill into the following days.
+const (
+	DefaultHorizonHours = 12
+	MaxHorizonHours     = 28 * 24 // Four weeks
+)
+
+// napSearchWindow limits where OptimizeSchedule tries a nap: a nap is a same-day decision, and
+// trying every slot of a multi-week horizon would re-plan the whole horizon for each one.
+const napSearchWindow = 24 * time.Hour
+
+// validateHorizon checks the horizon is between an hour and MaxHorizonHours (zero is the default).
+func (o ScheduleOptions) validateHorizon() error {
+	if o.HorizonHours != 0 && (o.HorizonHours < 1 || o.HorizonHours > MaxHorizonHours) {
+		return fmt.Errorf("horizon_hours must be between 1 and %d, got %d", MaxHorizonHours, o.HorizonHours)
+	}
+	return nil
+}
+
+// horizon is how far ahead OptimizeSchedule books.
+func (o ScheduleOptions) horizon() time.Duration {
+	if o.HorizonHours == 0 {
+		return DefaultHorizonHours * time.Hour
+	}
+	return time.Duration(o.HorizonHours) * time.Hour
+}
+
+// withNights makes sure the model knows when the user will be asleep up to 'until' (see
+// PredictNights). Slots inside the nights are then booked out, so plans skip them instead of
+// scheduling work at 3am.
+func withNights(model CapacityModel, from, until time.Time) CapacityModel {
+	current := model.Biology()
+	nights := PredictNights(current, from, until)
+	if len(nights) == len(current.Sleep) {
+		return model
+	}
+	params := current.anchored()
+	params.Sleep = nights
+	return model.WithBiology(params)
+}
+
+// PredictNights returns the params' sleep log with the nights between from and until it does
+// not cover filled in as PredictSleepWindow expects them: a short-slept night owl is not put to
+// bed at 23:00. Without any sleep to predict from (no log and no WakeTime), or if the prediction
+// fails, the default routine fills in. The params' own log is not modified.
+func PredictNights(params BioParams, from, until time.Time) SleepLog {
+	params = params.anchored()
+	if len(params.Sleep) == 0 {
+		nights, err := params.Sleep.Extend(DefaultRoutine, params.local(from), until)
+		if err != nil {
+			return params.Sleep
+		}
+		return nights
+	}
+
+	// 1. Predict from the end of the last night we know about, or from 'from' if that is later
+	cursor := from
+	for _, ep := range params.Sleep {
+		if !ep.Nap && ep.End.After(cursor) {
+			cursor = ep.End
+		}
+	}
+
+	// 2. Each predicted night is logged before predicting the next, so its sleep carries over
+	params.Sleep = append(SleepLog(nil), params.Sleep...)
+	for cursor.Before(until) {
+		window, err := PredictSleepWindow(params, cursor)
+		if err != nil {
+			if nights, err := params.Sleep.Extend(DefaultRoutine, params.local(cursor), until); err == nil {
+				return nights
+			}
+			break
+		}
+		night := SleepEpisode{Start: window.Bedtime, End: window.WakeTime, Planned: true}
+		if !night.Start.Before(until) || params.Sleep.overlaps(night) {
+			break // A planned nap in the way is left to the user
+		}
+		params.Sleep = append(params.Sleep, night)
+		cursor = night.End
+	}
+	return params.Sleep
+}
+
+// DayPlan is one calendar day (in the user's zone) of a schedule.
+type DayPlan struct {
+	Date  string         `json:"date"` // YYYY-MM-DD
+	Items []ScheduleItem `json:"items"`
+}
+
+// Plan is a schedule grouped by day, with the tasks that did not fit listed separately.
+type Plan struct {
+	Days        []DayPlan      `json:"days"`
+	Unscheduled []ScheduleItem `json:"unscheduled,omitempty"`
+}
+
+// GroupByDay splits a schedule sorted by OptimizeSchedule into days. Days without any items are left out.
+func GroupByDay(schedule []ScheduleItem) Plan {
+	var plan Plan
+	for _, item := range schedule {
+		start, ok := item.start()
+		if !ok {
+			plan.Unscheduled = append(plan.Unscheduled, item)
+			continue
+		}
+		date := start.Format("2006-01-02")
+		if n := len(plan.Days); n == 0 || plan.Days[n-1].Date != date {
+			plan.Days = append(plan.Days, DayPlan{Date: date})
+		}
+		day := &plan.Days[len(plan.Days)-1]
+		day.Items = append(day.Items, item)
+	}
+	return plan
+}
@@ -0,0 +1,63 @@
+package biomodel
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithNightsUsesPredictedSleep(t *testing.T) {
+	// A night owl: bed at 01:30, up at 09:30, with the body clock to match
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	var log SleepLog
+	for k := 6; k >= 0; k-- {
+		bed := day.Add(-time.Duration(k)*24*time.Hour + 90*time.Minute)
+		log = append(log, SleepEpisode{Start: bed, End: bed.Add(8 * time.Hour)})
+	}
+	params := BioParams{Sleep: log, ChronotypeLag: 2.5}
+	from := day.Add(12 * time.Hour)
+
+	want, err := PredictSleepWindow(params, from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nights := withNights(AverageModel{Params: params}, from, from.Add(24*time.Hour)).Biology().Sleep
+	if len(nights) != len(log)+1 {
+		t.Fatalf("want one predicted night added to the %d logged, got %d episodes", len(log), len(nights))
+	}
+	got := nights[len(nights)-1]
+	if !got.Start.Equal(want.Bedtime) || !got.End.Equal(want.WakeTime) || !got.Planned {
+		t.Errorf("got night %s-%s, want the predicted %s-%s", got.Start.Format("15:04"), got.End.Format("15:04"),
+			want.Bedtime.Format("15:04"), want.WakeTime.Format("15:04"))
+	}
+	if got.Start.Hour() == 23 && got.Start.Minute() == 0 {
+		t.Errorf("night owl put to bed at the default routine's 23:00")
+	}
+}
+
+func TestOptimizeScheduleSkipsTonightAtTheDefaultHorizon(t *testing.T) {
+	start := time.Date(2026, 3, 10, 18, 0, 0, 0, time.UTC)
+	sleep, _ := DefaultRoutine.Nights(start, 0)
+	model := AverageModel{Params: BioParams{Sleep: sleep}}
+
+	tonight, err := PredictSleepWindow(model.Params, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tasks := []Task{{Name: "a", Duration: 120, Effort: 2}, {Name: "b", Duration: 120, Effort: 2}, {Name: "c", Duration: 120, Effort: 2}}
+	schedule, err := OptimizeSchedule(tasks, start, model, ScheduleOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range schedule {
+		begin, ok := item.start()
+		if !ok {
+			continue
+		}
+		end := begin.Add(time.Duration(item.Duration) * time.Minute)
+		if begin.Before(tonight.WakeTime) && tonight.Bedtime.Before(end) {
+			t.Errorf("%q is booked %s-%s, inside the predicted night %s-%s", item.TaskName,
+				begin.Format("15:04"), end.Format("15:04"), tonight.Bedtime.Format("15:04"), tonight.WakeTime.Format("15:04"))
+		}
+	}
+}
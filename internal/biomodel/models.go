@@ -0,0 +1,165 @@
+package biomodel
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// CapacityModel turns a user's biology into a capacity score at a point in time.
+// Every implementation shares the same Process S, C and W; they only differ in how
+// those are integrated into BioState.TotalCapacity (the ultradian wave, when enabled,
+// is added on top by all of them). This lets us A/B models without touching the scheduler.
+type CapacityModel interface {
+	Name() string
+	State(targetTime time.Time) BioState
+
+	// Biology returns the parameters the model evaluates, and WithBiology a copy of the model
+	// evaluating different ones. Planners use them to ask "what if", e.g. with a nap added.
+	Biology() BioParams
+	WithBiology(params BioParams) CapacityModel
+}
+
+// Model names accepted by NewCapacityModel.
+const (
+	ModelAverage        = "average"
+	ModelMultiplicative = "multiplicative"
+	ModelAlertness      = "alertness"
+	ModelWeighted       = "weighted"
+)
+
+// Weights are the per-user coefficients of the WeightedModel.
+type Weights struct {
+	Homeostatic float64 `json:"homeostatic"` // Weight of Process S freshness
+	Circadian   float64 `json:"circadian"`   // Weight of Process C arousal
+	Inertia     float64 `json:"inertia"`     // Capacity lost per unit of Process W
+}
+
+// DefaultWeights reproduce the AverageModel, so an untuned WeightedModel behaves like the default.
+var DefaultWeights = Weights{Homeostatic: 1.0, Circadian: 1.0, Inertia: inertiaWeight}
+
+// NewCapacityModel looks up a model by name. An empty name selects the AverageModel.
+// The weights are only used by the WeightedModel.
+func NewCapacityModel(name string, params BioParams, weights Weights) (CapacityModel, error) {
+	switch name {
+	case "", ModelAverage:
+		return AverageModel{Params: params}, nil
+	case ModelMultiplicative:
+		return MultiplicativeModel{Params: params}, nil
+	case ModelAlertness:
+		return AlertnessModel{Params: params}, nil
+	case ModelWeighted:
+		// A negative inertia weight would turn grogginess into a boost
+		if weights.Homeostatic < 0 || weights.Circadian < 0 || weights.Inertia < 0 || weights.Homeostatic+weights.Circadian == 0 {
+			return nil, fmt.Errorf("weighted model needs non-negative weights with a positive homeostatic + circadian sum, got %+v", weights)
+		}
+		return WeightedModel{Params: params, Weights: weights}, nil
+	default:
+		return nil, fmt.Errorf("unknown capacity model %q", name)
+	}
+}
+
+// AverageModel is the original TardiGo heuristic:
+// capacity is the average of your freshness and your circadian drive,
+// minus whatever grogginess is left over from waking up.
+type AverageModel struct {
+	Params BioParams
+}
+
+func (m AverageModel) Name() string       { return ModelAverage }
+func (m AverageModel) Biology() BioParams { return m.Params }
+func (m AverageModel) WithBiology(params BioParams) CapacityModel {
+	m.Params = params
+	return m
+}
+
+func (m AverageModel) State(targetTime time.Time) BioState {
+	state := m.Params.components(targetTime)
+	state.TotalCapacity = state.limit((state.ProcessS+state.ProcessC)/2.0 - inertiaWeight*state.ProcessW + state.Ultradian)
+	return state
+}
+
+// MultiplicativeModel treats the processes as gates rather than votes: capacity is the
+// geometric mean of freshness and circadian drive, scaled down by inertia.
+// Either process being low drags the whole score down, unlike the average.
+type MultiplicativeModel struct {
+	Params BioParams
+}
+
+func (m MultiplicativeModel) Name() string       { return ModelMultiplicative }
+func (m MultiplicativeModel) Biology() BioParams { return m.Params }
+func (m MultiplicativeModel) WithBiology(params BioParams) CapacityModel {
+	m.Params = params
+	return m
+}
+
+func (m MultiplicativeModel) State(targetTime time.Time) BioState {
+	state := m.Params.components(targetTime)
+	state.TotalCapacity = state.limit(math.Sqrt(state.ProcessS*state.ProcessC)*(1.0-inertiaWeight*state.ProcessW) + state.Ultradian)
+	return state
+}
+
+// Constants of the Folkard & Åkerstedt alertness model, in its native alertness units.
+const (
+	alertnessSLower     = 2.4  // S after extended wakefulness
+	alertnessSUpper     = 14.3 // S when fully rested
+	alertnessCAmplitude = 2.5  // Half the peak-to-trough swing of C
+	alertnessWMagnitude = 5.72 // W at the moment of waking
+)
+
+// AlertnessModel follows Folkard & Åkerstedt: alertness is the sum S + C + W in their
+// native units, then rescaled so the best possible (rested, circadian peak, no inertia)
+// is 1.0 and the worst possible without inertia is 0.0.
+type AlertnessModel struct {
+	Params BioParams
+}
+
+func (m AlertnessModel) Name() string       { return ModelAlertness }
+func (m AlertnessModel) Biology() BioParams { return m.Params }
+func (m AlertnessModel) WithBiology(params BioParams) CapacityModel {
+	m.Params = params
+	return m
+}
+
+func (m AlertnessModel) State(targetTime time.Time) BioState {
+	state := m.Params.components(targetTime)
+
+	s := alertnessSLower + (alertnessSUpper-alertnessSLower)*state.ProcessS
+	c := alertnessCAmplitude * (2.0*state.ProcessC - 1.0)
+	w := -alertnessWMagnitude * state.ProcessW
+
+	minAlert := alertnessSLower - alertnessCAmplitude
+	maxAlert := alertnessSUpper + alertnessCAmplitude
+	state.TotalCapacity = state.limit((s+c+w-minAlert)/(maxAlert-minAlert) + state.Ultradian)
+	return state
+}
+
+// WeightedModel is the AverageModel with per-user weights, e.g. for someone whose focus
+// tracks time of day far more than hours awake.
+type WeightedModel struct {
+	Params  BioParams
+	Weights Weights
+}
+
+func (m WeightedModel) Name() string       { return ModelWeighted }
+func (m WeightedModel) Biology() BioParams { return m.Params }
+func (m WeightedModel) WithBiology(params BioParams) CapacityModel {
+	m.Params = params
+	return m
+}
+
+func (m WeightedModel) State(targetTime time.Time) BioState {
+	state := m.Params.components(targetTime)
+	w := m.Weights
+	if w.Homeostatic+w.Circadian <= 0 {
+		w = DefaultWeights
+	}
+	blended := (w.Homeostatic*state.ProcessS + w.Circadian*state.ProcessC) / (w.Homeostatic + w.Circadian)
+	state.TotalCapacity = state.limit(blended - w.Inertia*state.ProcessW + state.Ultradian)
+	return state
+}
+
+// clamp01 clamps a capacity score between 0 and 1.
+func clamp01(v float64) float64 {
+	return math.Max(0.0, math.Min(1.0, v))
+}
@@ -0,0 +1,27 @@
+package biomodel
+
+import "testing"
+
+func TestNewCapacityModelChecksWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights Weights
+		ok      bool
+	}{
+		{"defaults", DefaultWeights, true},
+		{"no inertia", Weights{Homeostatic: 1, Circadian: 1}, true},
+		{"circadian only", Weights{Circadian: 1}, true},
+		{"negative homeostatic", Weights{Homeostatic: -1, Circadian: 2}, false},
+		{"negative circadian", Weights{Homeostatic: 2, Circadian: -1}, false},
+		{"negative inertia", Weights{Homeostatic: 1, Circadian: 1, Inertia: -0.5}, false},
+		{"zero sum", Weights{Inertia: 1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCapacityModel(ModelWeighted, BioParams{}, tt.weights)
+			if (err == nil) != tt.ok {
+				t.Errorf("weights %+v: got error %v, want ok %v", tt.weights, err, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package biomodel
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Monte Carlo defaults. FatigueRate and ChronotypeLag are educated guesses until a user has been
+// fitted, so we sample them and report how much capacity moves.
+const (
+	DefaultEnsembleSize = 50
+	ensembleSeed        = 1 // Fixed so the same request always produces the same bands and schedule
+
+	minSampledFatigue = 8.0
+	maxSampledFatigue = 30.0
+	maxSampledLag     = 6.0
+)
+
+// Uncertainty is the spread (one standard deviation) of a user's parameters around their values.
+type Uncertainty struct {
+	FatigueRateSD   float64 `json:"fatigue_rate_sd"`   // Hours
+	ChronotypeLagSD float64 `json:"chronotype_lag_sd"` // Hours
+}
+
+// Typical spreads: population-wide before we know anything, narrower after a questionnaire and
+// narrower still once parameters have been fitted to the user's own reports.
+var (
+	DefaultUncertainty    = Uncertainty{FatigueRateSD: 2.5, ChronotypeLagSD: 1.5}
+	CalibratedUncertainty = Uncertainty{FatigueRateSD: 2.0, ChronotypeLagSD: 0.75}
+	FittedUncertainty     = Uncertainty{FatigueRateSD: 1.0, ChronotypeLagSD: 0.5}
+)
+
+// Validate rejects negative spreads.
+func (u Uncertainty) Validate() error {
+	if u.FatigueRateSD < 0 || u.ChronotypeLagSD < 0 {
+		return fmt.Errorf("uncertainty must not be negative, got %+v", u)
+	}
+	return nil
+}
+
+// CapacityBand summarizes the sampled capacities at one point in time.
+type CapacityBand struct {
+	Mean float64 `json:"mean"`
+	P10  float64 `json:"p10"` // Pessimistic: 90% of samples are at least this high
+	P90  float64 `json:"p90"` // Optimistic: only 10% of samples are higher
+}
+
+// sampledParams is one draw of the uncertain parameters.
+type sampledParams struct {
+	fatigueRate, chronotypeLag float64
+}
+
+// Ensemble is a CapacityModel that also knows how uncertain it is. State comes from the central
+// model, so an Ensemble schedules like the model it wraps; Band adds the spread across members
+// whose FatigueRate and ChronotypeLag are sampled from the Uncertainty.
+type Ensemble struct {
+	Central CapacityModel
+	members []CapacityModel
+	draws   []sampledParams
+}
+
+// NewEnsemble samples n members around the central model. n <= 0 uses DefaultEnsembleSize.
+func NewEnsemble(central CapacityModel, u Uncertainty, n int) *Ensemble {
+	if n <= 0 {
+		n = DefaultEnsembleSize
+	}
+	params := central.Biology()
+	rng := rand.New(rand.NewSource(ensembleSeed))
+
+	e := &Ensemble{Central: central}
+	for i := 0; i < n; i++ {
+		draw := sampledParams{
+			fatigueRate: math.Max(minSampledFatigue, math.Min(maxSampledFatigue,
+				params.fatigueRate()+rng.NormFloat64()*u.FatigueRateSD)),
+			chronotypeLag: math.Max(-maxSampledLag, math.Min(maxSampledLag,
+				params.ChronotypeLag+rng.NormFloat64()*u.ChronotypeLagSD)),
+		}
+		e.draws = append(e.draws, draw)
+	}
+	e.members = e.build(params)
+	return e
+}
+
+// build creates one model per draw with the drawn values applied to params.
+func (e *Ensemble) build(params BioParams) []CapacityModel {
+	members := make([]CapacityModel, len(e.draws))
+	for i, draw := range e.draws {
+		p := params
+		p.FatigueRate, p.ChronotypeLag = draw.fatigueRate, draw.chronotypeLag
+		members[i] = e.Central.WithBiology(p)
+	}
+	return members
+}
+
+func (e *Ensemble) Name() string                        { return e.Central.Name() }
+func (e *Ensemble) State(targetTime time.Time) BioState { return e.Central.State(targetTime) }
+func (e *Ensemble) Biology() BioParams                  { return e.Central.Biology() }
+
+// WithBiology keeps the same draws, so "what if" comparisons (e.g. with a nap) stay like-for-like.
+func (e *Ensemble) WithBiology(params BioParams) CapacityModel {
+	out := &Ensemble{Central: e.Central.WithBiology(params), draws: e.draws}
+	out.members = out.build(params)
+	return out
+}
+
+// Band evaluates every member at targetTime.
+func (e *Ensemble) Band(targetTime time.Time) CapacityBand {
+	values := make([]float64, len(e.members))
+	sum := 0.0
+	for i, m := range e.members {
+		values[i] = m.State(targetTime).TotalCapacity
+		sum += values[i]
+	}
+	sort.Float64s(values)
+	return CapacityBand{
+		Mean: sum / float64(len(values)),
+		P10:  percentile(values, 0.10),
+		P90:  percentile(values, 0.90),
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
@@ -0,0 +1,134 @@
+package biomodel
+
+import (
+	"fmt"
+	"time"
+)
+
+// Nap inertia depends on the sleep stage the nap is cut off in. Short naps stay in light sleep,
+// naps of 30-90 minutes are usually interrupted in slow-wave sleep (the worst grogginess), and a
+// full ~90 minute cycle ends back in light/REM sleep.
+const (
+	napLightSleep   = 30 * time.Minute
+	napFullCycle    = 90 * time.Minute
+	napLightInertia = 0.25 // W at waking from light sleep
+	napDeepInertia  = 1.0  // W at waking from slow-wave sleep, as bad as a night
+	napCycleInertia = 0.5  // W at waking at the end of a full cycle
+
+	napSearchStep  = 10 * time.Minute
+	napBlockStep   = 15 * time.Minute
+	napMinimumGain = 0.01          // A nap must lift the block's mean capacity by at least this much
+	napAwakeGap    = 2 * time.Hour // Closer to a night than this, a "nap" is sleeping in or turning in early
+	napAnchorNight = 8 * time.Hour
+)
+
+// NapLengths are the nap durations RecommendNap considers: power naps and a full cycle.
+var NapLengths = []time.Duration{10 * time.Minute, 20 * time.Minute, 30 * time.Minute, 60 * time.Minute, 90 * time.Minute}
+
+// napInertia returns Process W at the end of a nap of the given length.
+func napInertia(length time.Duration) float64 {
+	switch {
+	case length <= napLightSleep:
+		return napLightInertia
+	case length < napFullCycle:
+		return napDeepInertia
+	default:
+		return napCycleInertia
+	}
+}
+
+// TimeBlock is a stretch of time the user needs to perform in, e.g. an evening on-call shift.
+type TimeBlock struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// NapPlan is a recommended nap and what it buys for the target block.
+type NapPlan struct {
+	Nap      SleepEpisode `json:"nap"`
+	Capacity float64      `json:"block_capacity"`    // Mean capacity over the block with the nap
+	Baseline float64      `json:"baseline_capacity"` // Mean capacity over the block without it
+}
+
+// Gain returns how much the nap lifts the block's mean capacity.
+func (p NapPlan) Gain() float64 {
+	return p.Capacity - p.Baseline
+}
+
+// RecommendNap searches nap start times between 'from' and the block, and the NapLengths, for the nap
+// that maximizes mean capacity over the block. Naps must end before the block starts and keep
+// napAwakeGap clear of sleep the model already knows about. It returns false when no nap helps noticeably.
+func RecommendNap(model CapacityModel, from time.Time, block TimeBlock) (NapPlan, bool, error) {
+	if !block.End.After(block.Start) {
+		return NapPlan{}, false, fmt.Errorf("block starting %s does not end after it starts", block.Start.Format(time.RFC3339))
+	}
+	if block.Start.Before(from) {
+		return NapPlan{}, false, fmt.Errorf("block starting %s is already under way", block.Start.Format(time.RFC3339))
+	}
+
+	params := model.Biology().anchored()
+	model = model.WithBiology(params)
+	baseline := blockCapacity(model, block)
+	best := NapPlan{Baseline: baseline, Capacity: baseline}
+
+	for start := from; start.Before(block.Start); start = start.Add(napSearchStep) {
+		for _, length := range NapLengths {
+			nap := SleepEpisode{Start: start, End: start.Add(length), Planned: true, Nap: true}
+			if nap.End.After(block.Start) || params.Sleep.crowds(nap) {
+				continue
+			}
+
+			capacity := blockCapacity(model.WithBiology(params.withNap(nap)), block)
+			if capacity > best.Capacity {
+				best.Nap = nap
+				best.Capacity = capacity
+			}
+		}
+	}
+
+	if best.Gain() < napMinimumGain {
+		return NapPlan{Baseline: baseline, Capacity: baseline}, false, nil
+	}
+	return best, true, nil
+}
+
+// anchored returns a copy of the params that has a sleep log, so naps can be integrated into it.
+// Without one, the WakeTime anchor becomes the end of an ordinary night.
+func (b BioParams) anchored() BioParams {
+	if len(b.Sleep) == 0 && !b.WakeTime.IsZero() {
+		b.Sleep = SleepLog{{Start: b.WakeTime.Add(-napAnchorNight), End: b.WakeTime}}
+	}
+	return b
+}
+
+// nextNight returns the first sleep episode after t that is not a nap.
+func (b BioParams) nextNight(t time.Time) (SleepEpisode, bool) {
+	for _, ep := range b.sortedSleep() {
+		if !ep.Nap && !ep.Start.Before(t) {
+			return ep, true
+		}
+	}
+	return SleepEpisode{}, false
+}
+
+// crowds reports whether the nap overlaps sleep in the log, or comes within napAwakeGap of it.
+func (l SleepLog) crowds(nap SleepEpisode) bool {
+	return l.overlaps(SleepEpisode{Start: nap.Start.Add(-napAwakeGap), End: nap.End.Add(napAwakeGap)})
+}
+
+// withNap returns a copy of the params with the nap added to the sleep log.
+func (b BioParams) withNap(nap SleepEpisode) BioParams {
+	sleep := make(SleepLog, 0, len(b.Sleep)+1)
+	b.Sleep = append(append(sleep, b.Sleep...), nap)
+	return b
+}
+
+// blockCapacity is the mean capacity over the block.
+func blockCapacity(model CapacityModel, block TimeBlock) float64 {
+	total, n := 0.0, 0
+	for t := block.Start; t.Before(block.End); t = t.Add(napBlockStep) {
+		total += model.State(t).TotalCapacity
+		n++
+	}
+	return total / float64(n)
+}
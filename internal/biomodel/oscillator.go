@@ -0,0 +1,271 @@
+package biomodel
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Illuminance (lux) assumed for each kind of light exposure when no measurement is given.
+var lightKinds = map[string]float64{
+	"outdoors": 10000,
+	"indoors":  150,
+	"screen":   50,
+	"dark":     0,
+}
+
+// LightExposure is a stretch of roughly constant light, either measured (Lux) or described (Kind).
+type LightExposure struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Lux   float64   `json:"lux,omitempty"`
+	Kind  string    `json:"kind,omitempty"` // "outdoors", "indoors", "screen" or "dark"; used when Lux is 0
+}
+
+// LightLog is a time series of light exposure. Gaps are filled in from the sleep log:
+// dark while asleep, ordinary indoor light while awake.
+type LightLog []LightExposure
+
+// Validate checks that every entry has a sane span and either a lux value or a known kind.
+func (l LightLog) Validate() error {
+	for _, e := range l {
+		if !e.End.After(e.Start) {
+			return fmt.Errorf("light exposure starting %s does not end after it starts", e.Start.Format(time.RFC3339))
+		}
+		if e.Lux < 0 {
+			return fmt.Errorf("light exposure starting %s has negative lux", e.Start.Format(time.RFC3339))
+		}
+		if _, ok := lightKinds[e.Kind]; e.Lux == 0 && !ok {
+			return fmt.Errorf("light exposure starting %s: unknown kind %q", e.Start.Format(time.RFC3339), e.Kind)
+		}
+	}
+	return nil
+}
+
+// Kronauer oscillator constants (Forger, Jewett & Kronauer, 1999). Time is in hours.
+const (
+	oscTauX   = 24.2 // Intrinsic period of the pacemaker
+	oscMu     = 0.23 // Stiffness of the van der Pol limit cycle
+	oscQ      = 1.0 / 3.0
+	oscK      = 0.55
+	oscG      = 33.75  // Gain of the light drive
+	oscAlpha0 = 0.05   // Photoreceptor activation rate at I0
+	oscBeta   = 0.0075 // Photoreceptor recovery rate
+	oscI0     = 9500.0
+	oscP      = 0.5
+
+	oscStep   = 6 * time.Minute
+	oscWarmup = 7 * 24 * time.Hour // Lets the initial conditions settle onto the limit cycle
+
+	maxLightRuns    = 16                  // Integrated light histories kept in memory (see lightRuns)
+	maxLightRunSpan = 60 * 24 * time.Hour // Steps kept per run; later times integrate on from the last one
+)
+
+// oscillatorState is the pacemaker (x, xc) plus the fraction of activated photoreceptors (n).
+type oscillatorState struct {
+	x, xc, n float64
+}
+
+// oscillatorStart is a point on the entrained limit cycle around midnight, so warm-up converges quickly.
+var oscillatorStart = oscillatorState{x: -0.5, xc: -1.0, n: 0.0}
+
+// lightOscillator computes Process C from the limit-cycle pacemaker driven by light.
+// Its phase is set by the light history alone, so ChronotypeLag and Itinerary do not apply.
+// The integration runs from a week before the first light entry; it is kept (see lightRuns),
+// so sampling a day of slots integrates the history once rather than once per slot.
+func (b *BioParams) lightOscillator(targetTime time.Time) float64 {
+	start := targetTime
+	for _, e := range b.Light {
+		if e.Start.Before(start) {
+			start = e.Start
+		}
+	}
+	logged := start.Before(targetTime) // The run starts from the light log, not from targetTime
+	start = start.Add(-oscWarmup)
+
+	// Times before the light log starts get a run of their own, not worth keeping
+	var run *lightRun
+	if logged {
+		run = lightRuns.run(b, start)
+	}
+	var state oscillatorState
+	if run != nil {
+		state = run.at(b, targetTime)
+	} else {
+		state = b.integrateOscillator(start, targetTime)
+	}
+
+	// x tracks core body temperature, which tracks alertness; it swings roughly between -1 and 1.
+	return clamp01((state.x + 1.0) / 2.0)
+}
+
+// integrateOscillator runs the oscillator from start to targetTime in oscStep steps.
+func (b *BioParams) integrateOscillator(start, targetTime time.Time) oscillatorState {
+	state := oscillatorStart
+	for t := start; t.Before(targetTime); t = t.Add(oscStep) {
+		h := oscStep.Hours()
+		if remaining := targetTime.Sub(t); remaining < oscStep {
+			h = remaining.Hours()
+		}
+		state = state.step(b.lux(t), h)
+	}
+	return state
+}
+
+// lightRun is one integration of the oscillator, kept on the oscStep grid so later evaluations
+// continue from the nearest step instead of starting over. Only the light the oscillator sees
+// (light log, sleep log, time zone) and the start decide the run, so Ensemble members with
+// different fatigue rates share one.
+type lightRun struct {
+	mu     sync.Mutex
+	start  time.Time
+	states []oscillatorState // states[k] is the oscillator at start + k*oscStep, up to maxLightRunSpan
+	used   time.Time         // Last handed out by the cache; guarded by the cache's lock
+}
+
+// at returns the oscillator at t, integrating (and keeping) any steps up to t not yet computed.
+// The result is the same as integrateOscillator(start, t).
+func (r *lightRun) at(b *BioParams, t time.Time) oscillatorState {
+	if !t.After(r.start) {
+		return oscillatorStart
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := int(t.Sub(r.start) / oscStep)
+	kept := min(k, int(maxLightRunSpan/oscStep))
+	for len(r.states) <= kept {
+		last := len(r.states) - 1
+		r.states = append(r.states, r.states[last].step(b.lux(r.start.Add(time.Duration(last)*oscStep)), oscStep.Hours()))
+	}
+
+	// Steps past the span and the last partial step are not kept, so a run far ahead of its
+	// light log costs time rather than memory
+	state := r.states[kept]
+	for i := kept; i < k; i++ {
+		state = state.step(b.lux(r.start.Add(time.Duration(i)*oscStep)), oscStep.Hours())
+	}
+	stepStart := r.start.Add(time.Duration(k) * oscStep)
+	if rest := t.Sub(stepStart); rest > 0 {
+		state = state.step(b.lux(stepStart), rest.Hours())
+	}
+	return state
+}
+
+// lightRunCache holds the integrated runs, keyed by everything that determines them. It keeps at
+// most maxLightRuns, dropping the least recently used first. It is safe for concurrent use.
+type lightRunCache struct {
+	mu   sync.Mutex
+	runs map[string]*lightRun
+}
+
+var lightRuns = lightRunCache{runs: make(map[string]*lightRun)}
+
+// run returns the run for the params' light from start, creating it if needed. It returns nil if
+// the params cannot be keyed, and the caller integrates directly.
+func (c *lightRunCache) run(b *BioParams, start time.Time) *lightRun {
+	encoded, err := json.Marshal(struct {
+		Start    time.Time
+		Light    LightLog
+		Sleep    SleepLog
+		TimeZone string
+	}{start, b.Light, b.Sleep, b.TimeZone})
+	if err != nil {
+		return nil
+	}
+	sum := sha256.Sum256(encoded)
+	key := hex.EncodeToString(sum[:])
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if r, ok := c.runs[key]; ok {
+		r.used = now
+		return r
+	}
+	for len(c.runs) >= maxLightRuns {
+		c.evictOldest()
+	}
+	r := &lightRun{start: start, states: []oscillatorState{oscillatorStart}, used: now}
+	c.runs[key] = r
+	return r
+}
+
+// evictOldest drops the least recently used run. The caller must hold c.mu.
+func (c *lightRunCache) evictOldest() {
+	var oldest string
+	for key, r := range c.runs {
+		if oldest == "" || r.used.Before(c.runs[oldest].used) {
+			oldest = key
+		}
+	}
+	delete(c.runs, oldest)
+}
+
+// step advances the oscillator by h hours under constant light using classic RK4.
+func (s oscillatorState) step(lux, h float64) oscillatorState {
+	k1 := s.derivative(lux)
+	k2 := s.add(k1, h/2).derivative(lux)
+	k3 := s.add(k2, h/2).derivative(lux)
+	k4 := s.add(k3, h).derivative(lux)
+	return oscillatorState{
+		x:  s.x + h/6*(k1.x+2*k2.x+2*k3.x+k4.x),
+		xc: s.xc + h/6*(k1.xc+2*k2.xc+2*k3.xc+k4.xc),
+		n:  s.n + h/6*(k1.n+2*k2.n+2*k3.n+k4.n),
+	}
+}
+
+// add returns s + h*d.
+func (s oscillatorState) add(d oscillatorState, h float64) oscillatorState {
+	return oscillatorState{x: s.x + h*d.x, xc: s.xc + h*d.xc, n: s.n + h*d.n}
+}
+
+// derivative evaluates the Kronauer equations:
+//
+//	dn/dt  = 60 [ α(I)(1 - n) - βn ]
+//	B      = G α(I)(1 - n)(1 - 0.4x)(1 - 0.4xc)
+//	dx/dt  = π/12 [ xc + μ(x/3 + 4x³/3 - 256x⁷/105) + B ]
+//	dxc/dt = π/12 [ qB·xc - x((24 / 0.99729τx)² + kB) ]
+func (s oscillatorState) derivative(lux float64) oscillatorState {
+	alpha := oscAlpha0 * math.Pow(lux/oscI0, oscP)
+	drive := oscG * alpha * (1 - s.n) * (1 - 0.4*s.x) * (1 - 0.4*s.xc)
+	period := 24.0 / (0.99729 * oscTauX)
+
+	return oscillatorState{
+		x:  math.Pi / 12 * (s.xc + oscMu*(s.x/3+4*math.Pow(s.x, 3)/3-256*math.Pow(s.x, 7)/105) + drive),
+		xc: math.Pi / 12 * (oscQ*drive*s.xc - s.x*(period*period+oscK*drive)),
+		n:  60 * (alpha*(1-s.n) - oscBeta*s.n),
+	}
+}
+
+// lux returns the light level at t: the logged value if any, otherwise dark while asleep and
+// indoor light while awake.
+func (b *BioParams) lux(t time.Time) float64 {
+	for _, e := range b.Light {
+		if !t.Before(e.Start) && t.Before(e.End) {
+			if e.Lux > 0 {
+				return e.Lux
+			}
+			return lightKinds[e.Kind]
+		}
+	}
+
+	if len(b.Sleep) > 0 {
+		for _, ep := range b.Sleep {
+			if ep.Contains(t) {
+				return lightKinds["dark"]
+			}
+		}
+		return lightKinds["indoors"]
+	}
+
+	// No sleep log either: assume the default routine's night
+	if hour := b.local(t).Hour(); hour >= 23 || hour < 7 {
+		return lightKinds["dark"]
+	}
+	return lightKinds["indoors"]
+}
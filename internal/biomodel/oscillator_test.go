@@ -0,0 +1,83 @@
+package biomodel
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLightRunMatchesDirectIntegration(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	params := BioParams{
+		Sleep: SleepLog{{Start: day.Add(-time.Hour), End: day.Add(7 * time.Hour)}},
+		Light: LightLog{
+			{Start: day.Add(8 * time.Hour), End: day.Add(9 * time.Hour), Kind: "outdoors"},
+			{Start: day.Add(21 * time.Hour), End: day.Add(23 * time.Hour), Kind: "screen"},
+		},
+	}
+	start := params.Light[0].Start.Add(-oscWarmup)
+
+	// Out of order and off the step grid, so the run both extends and reuses its steps
+	for _, offset := range []time.Duration{30 * time.Hour, 10*time.Hour + 7*time.Minute, 12 * time.Hour, 10 * time.Hour, 47*time.Hour + 59*time.Minute} {
+		target := day.Add(offset)
+		want := params.integrateOscillator(start, target)
+		if got := lightRuns.run(&params, start).at(&params, target); got != want {
+			t.Errorf("at +%v: kept run gives %+v, direct integration %+v", offset, got, want)
+		}
+	}
+}
+
+func TestLightRunKeepsAtMostItsSpan(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	params := BioParams{Light: LightLog{{Start: day.Add(8 * time.Hour), End: day.Add(9 * time.Hour), Kind: "outdoors"}}}
+	start := params.Light[0].Start.Add(-oscWarmup)
+	run := &lightRun{start: start, states: []oscillatorState{oscillatorStart}}
+
+	target := start.Add(maxLightRunSpan + 30*time.Hour + 7*time.Minute)
+	if got, want := run.at(&params, target), params.integrateOscillator(start, target); got != want {
+		t.Errorf("past the span: kept run gives %+v, direct integration %+v", got, want)
+	}
+	if limit := int(maxLightRunSpan/oscStep) + 1; len(run.states) > limit {
+		t.Errorf("run kept %d steps, want at most %d", len(run.states), limit)
+	}
+}
+
+func TestLightRunCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	params := BioParams{Light: LightLog{{Start: day.Add(8 * time.Hour), End: day.Add(9 * time.Hour), Kind: "outdoors"}}}
+	cache := lightRunCache{runs: make(map[string]*lightRun)}
+	startOf := func(i int) time.Time { return day.Add(time.Duration(i) * time.Hour) }
+
+	runs := make([]*lightRun, maxLightRuns)
+	for i := range runs {
+		runs[i] = cache.run(&params, startOf(i))
+		runs[i].used = time.Date(2000, 1, 1, 0, i, 0, 0, time.UTC) // Oldest first
+	}
+	cache.run(&params, startOf(0))            // Used again, so now the newest
+	cache.run(&params, startOf(maxLightRuns)) // One too many
+
+	kept := func(r *lightRun) bool {
+		for _, k := range cache.runs {
+			if k == r {
+				return true
+			}
+		}
+		return false
+	}
+	if len(cache.runs) != maxLightRuns {
+		t.Errorf("cache holds %d runs, want %d", len(cache.runs), maxLightRuns)
+	}
+	if !kept(runs[0]) {
+		t.Error("the run used last was evicted")
+	}
+	if kept(runs[1]) {
+		t.Error("the least recently used run was kept")
+	}
+}
+
+func BenchmarkLightOscillatorDay(b *testing.B) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	params := BioParams{Light: LightLog{{Start: day.Add(8 * time.Hour), End: day.Add(9 * time.Hour), Kind: "outdoors"}}}
+	for i := 0; i < b.N; i++ {
+		NewCapacityCurve(AverageModel{Params: params}, day.Add(10*time.Hour), day.Add(34*time.Hour), DefaultCurveResolution)
+	}
+}
@@ -0,0 +1,39 @@
+package biomodel
+
+import "math"
+
+// Mappings from capacity (0-1) to measures people know. They are anchored so a well-rested
+// morning (capacity ~0.85) reads as alert and error-free, and a night without sleep (capacity ~0.2)
+// matches typical laboratory results.
+//
+//	KSS        = 9 - 8 * capacity                                  (the inverse of ObservationFromKSS)
+//	Lapses     = 1 * e^(3 * (1 - capacity))                        (10-minute PVT, reaction time > 500 ms)
+//	Error rate = max(1, e^(2.5 * (0.8 - capacity)))                (relative to a rested baseline)
+const (
+	pvtBaselineLapses = 1.0 // Lapses a fully alert person still has in a 10-minute PVT
+	pvtLapseGrowth    = 3.0 // ~20 lapses at capacity 0, in line with 24h+ of sleep deprivation
+	errorReference    = 0.8 // Capacity at and above which errors are at their baseline rate
+	errorGrowth       = 2.5 // ~2x errors at capacity 0.5, ~4.5x at 0.2
+)
+
+// Performance translates a capacity score into predicted measures of alertness and performance.
+type Performance struct {
+	KSS             float64 `json:"kss"`                   // Karolinska Sleepiness Scale, 1 (extremely alert) to 9 (fighting sleep)
+	Lapses          float64 `json:"pvt_lapses"`            // Expected lapses in a 10-minute psychomotor vigilance test
+	ErrorMultiplier float64 `json:"error_rate_multiplier"` // Errors relative to the user's rested baseline (1.0 = baseline)
+}
+
+// PerformanceAt maps a capacity score (0-1) onto the Performance measures.
+func PerformanceAt(capacity float64) Performance {
+	capacity = clamp01(capacity)
+	return Performance{
+		KSS:             9 - 8*capacity,
+		Lapses:          pvtBaselineLapses * math.Exp(pvtLapseGrowth*(1-capacity)),
+		ErrorMultiplier: math.Max(1, math.Exp(errorGrowth*(errorReference-capacity))),
+	}
+}
+
+// Performance returns the predicted measures for this state.
+func (s BioState) Performance() Performance {
+	return PerformanceAt(s.TotalCapacity)
+}
@@ -1,99 +1,684 @@
 package biomodel
 
 import (
+	"fmt"
+	"maps"
+	"math"
 	"sort"
 	"time"
 )
 
-// Slot represents a 30-minute window of availability
+// Slot represents a window of availability, 30 minutes unless ScheduleOptions.SlotMinutes says otherwise
 type Slot struct {
-	Time     time.Time
-	Capacity float64
-	IsBooked bool
-}
-
-// OptimizeSchedule takes tasks and future capacity, and returns a calendar.
-func OptimizeSchedule(tasks []Task, startHour time.Time, bioParams BioParams) []ScheduleItem {
-	// 1. Generate Slots for the next 12 hours (30 min chunks)
-	var slots []Slot
-	for i := 0; i < 24; i++ { // 12 hours * 2 slots/hr
-		t := startHour.Add(time.Duration(i*30) * time.Minute)
-		state := bioParams.CalculateState(t)
-		slots = append(slots, Slot{
-			Time:     t,
-			Capacity: state.TotalCapacity,
-			IsBooked: false,
-		})
+	Time      time.Time
+	Capacity  float64
+	Band      *CapacityBand // Only set when planning with an Ensemble
+	Depletion float64       // Capacity drained by work booked before this slot
+	IsBooked  bool
+	Asleep    bool // Booked by sleep (or a nap) rather than by work
+}
+
+// ScheduleOptions tune OptimizeSchedule. The zero value reproduces the plain greedy planner.
+type ScheduleOptions struct {
+	AllowNap    bool `json:"allow_nap"`              // Insert a "Nap" item when it improves the overall schedule score
+	SlotMinutes int  `json:"slot_minutes,omitempty"` // Planning granularity: 5, 10, 15 or 30 (the default)
+
+	// How far ahead to book, in hours (default 12). Past a day, tasks spread over several days
+	// and the nights in between are skipped.
+	HorizonHours int `json:"horizon_hours,omitempty"`
+
+	// Fixed calendar events. Their time is blocked, and they tire the user like tasks do.
+	Events []Event `json:"events,omitempty"`
+
+	// Mandatory recovery after hard blocks, booked as explicit "Break" items. Nil books none.
+	Breaks *BreakPolicy `json:"breaks,omitempty"`
+
+	// Uncertainty-aware planning. Either option makes OptimizeSchedule sample an Ensemble
+	// (with DefaultUncertainty unless the model already is one).
+	Pessimistic bool       `json:"pessimistic"`          // Rank slots by their p10 capacity rather than the central estimate
+	BandFloor   *BandFloor `json:"band_floor,omitempty"` // Keep hard tasks out of slots whose p10 is too low
+}
+
+// BandFloor books tasks of at least Effort only where the p10 capacity is at least P10,
+// e.g. {Effort: 9, P10: 0.7}: "effort-9 work only where we are 90% sure capacity is above 0.7".
+type BandFloor struct {
+	Effort int     `json:"effort"`
+	P10    float64 `json:"p10"`
+}
+
+// Validate checks the floor is on the effort and capacity scales. A nil floor is valid.
+func (f *BandFloor) Validate() error {
+	if f == nil {
+		return nil
+	}
+	if f.Effort < 1 || f.Effort > 10 {
+		return fmt.Errorf("band floor effort must be 1-10, got %d", f.Effort)
+	}
+	if f.P10 < 0 || f.P10 > 1 {
+		return fmt.Errorf("band floor p10 must be between 0 and 1, got %.2f", f.P10)
 	}
+	return nil
+}
+
+// SlotSizes are the planning granularities OptimizeSchedule accepts, in minutes.
+var SlotSizes = []int{5, 10, 15, 30}
+
+// DefaultSlotMinutes is the granularity used when ScheduleOptions.SlotMinutes is zero.
+const DefaultSlotMinutes = 30
 
-	// 2. Sort Tasks: Hardest tasks first! (Heuristic: First Fit Descending)
+// Validate checks the slot size, the horizon, the break policy, the events and the band floor.
+func (o ScheduleOptions) Validate() error {
+	if o.SlotMinutes != 0 && !containsInt(SlotSizes, o.SlotMinutes) {
+		return fmt.Errorf("slot_minutes must be one of %v, got %d", SlotSizes, o.SlotMinutes)
+	}
+	if err := o.validateHorizon(); err != nil {
+		return err
+	}
+	if err := o.Breaks.Validate(); err != nil {
+		return err
+	}
+	for _, e := range o.Events {
+		if err := e.Validate(); err != nil {
+			return err
+		}
+	}
+	return o.BandFloor.Validate()
+}
+
+// slotLength is the duration of one planning slot.
+func (o ScheduleOptions) slotLength() time.Duration {
+	if o.SlotMinutes == 0 {
+		return DefaultSlotMinutes * time.Minute
+	}
+	return time.Duration(o.SlotMinutes) * time.Minute
+}
+
+// slotsFor is the number of whole slots a duration occupies, rounding up: with 30-minute slots
+// a 45-minute task takes two, the second of them only half used.
+func (o ScheduleOptions) slotsFor(d time.Duration) int {
+	slot := o.slotLength()
+	return int((d + slot - 1) / slot)
+}
+
+// banded reports whether the options need capacity bands.
+func (o ScheduleOptions) banded() bool {
+	return o.Pessimistic || o.BandFloor != nil
+}
+
+// Nap lengths the scheduler tries: a power nap that fits one slot, and a full cycle.
+var scheduleNapLengths = []time.Duration{20 * time.Minute, 90 * time.Minute}
+
+// NapTaskName is the TaskName of nap items inserted by OptimizeSchedule.
+const NapTaskName = "Nap"
+
+// OptimizeSchedule takes tasks and a capacity model, and returns a calendar.
+// It fails if a task or the options are invalid.
+func OptimizeSchedule(tasks []Task, startHour time.Time, model CapacityModel, opts ScheduleOptions) ([]ScheduleItem, error) {
+	if err := ValidateTasks(tasks); err != nil {
+		return nil, err
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	// 1. Sort Tasks: Hardest tasks first! (Heuristic: First Fit Descending)
 	// We want to book the "Deep Work" before the "Emails".
-	sort.Slice(tasks, func(i, j int) bool {
+	// Tasks with deadlines or windows go before the rest, though: they have fewer places to go.
+	// A task inherits the urgency of whatever depends on it, so the groundwork for a hard task
+	// is not left until the evening.
+	urgency := inheritUrgency(tasks)
+	sort.SliceStable(tasks, func(i, j int) bool {
+		ui, uj := urgency.of(tasks[i]), urgency.of(tasks[j])
+		if ui.constrained != uj.constrained {
+			return ui.constrained
+		}
+		if ui.effort != uj.effort {
+			return ui.effort > uj.effort
+		}
 		return tasks[i].Effort > tasks[j].Effort
 	})
 
-	var schedule []ScheduleItem
+	// 2. Planning against the pessimistic band needs the spread, not just the central estimate
+	if _, ok := unwrapCurve(model).(*Ensemble); opts.banded() && !ok {
+		model = NewEnsemble(unwrapCurve(model), DefaultUncertainty, 0)
+	}
 
-	// 3. The Allocation Loop
-	for _, task := range tasks {
-		slotsNeeded := task.Duration / 30
-		bestStartIdx := -1
-		bestScore := -1.0
+	// Make sure the model knows every night the plan crosses; an evening plan reaches one too
+	model = withNights(model, startHour, startHour.Add(opts.horizon()))
 
-		// Find the sequence of slots that maximizes (Capacity - Effort Match)
-		// We want High Capacity for High Effort.
-		for i := 0; i <= len(slots)-slotsNeeded; i++ {
+	// 3. Sleep debt rations how much hard work we book at all
+	budget := HighEffortBudget(model.State(startHour).SleepDebt)
 
-			// Check if slots are free
-			available := true
-			avgCap := 0.0
-			for j := 0; j < slotsNeeded; j++ {
-				if slots[i+j].IsBooked {
-					available = false
+	// 4. Plan the day as it stands
+	schedule, _ := allocate(tasks, buildSlots(model, startHour, opts), budget, opts)
+
+	// 5. Try fitting a nap in, and keep it only if the tasks end up in better slots
+	if opts.AllowNap {
+		if napped, ok := scheduleWithNap(tasks, startHour, model, budget, opts); ok {
+			schedule = napped
+		}
+	}
+
+	// Sort schedule by time for readability. RFC3339 with offsets does not sort as text
+	// across a DST switch, so compare the instants; UNSCHEDULED items go last.
+	sort.SliceStable(schedule, func(i, j int) bool {
+		ti, okI := schedule[i].start()
+		tj, okJ := schedule[j].start()
+		if !okI || !okJ {
+			return okI && !okJ
+		}
+		return ti.Before(tj)
+	})
+
+	return schedule, nil
+}
+
+// buildSlots generates the slots over the horizon (opts.slotLength() chunks), in the user's
+// time zone. They are read off a CapacityCurve: the model's own if it already is one covering the
+// day (e.g. from the API's cache), otherwise one sampled on the slot grid.
+// An Ensemble also fills in each slot's capacity band.
+func buildSlots(model CapacityModel, startHour time.Time, opts ScheduleOptions) []Slot {
+	slot := opts.slotLength()
+	startHour = model.Biology().local(startHour)
+	slots := make([]Slot, int(opts.horizon()/slot))
+	for i := range slots {
+		slots[i].Time = startHour.Add(time.Duration(i) * slot)
+	}
+	sampleSlots(slots, model, opts)
+	return slots
+}
+
+// sampleSlots (re)reads the capacity of each slot from the model, books out the ones the user is
+// asleep in and blocks the events. The slots are consecutive and not empty.
+func sampleSlots(slots []Slot, model CapacityModel, opts ScheduleOptions) {
+	curve := curveFor(model, slots[0].Time, slots[len(slots)-1].Time, opts.slotLength())
+	for i := range slots {
+		t := slots[i].Time
+		state := curve.State(t)
+		slots[i] = Slot{
+			Time:     t,
+			Capacity: state.TotalCapacity,
+			IsBooked: state.Asleep, // Nobody books tasks while asleep
+			Asleep:   state.Asleep,
+		}
+		if band, ok := curve.Band(t); ok {
+			slots[i].Band = &band
+		}
+	}
+	blockEvents(slots, opts.Events, opts)
+}
+
+// value is the capacity a slot is ranked by: its p10 when planning pessimistically.
+// Both are net of the depletion left by earlier bookings.
+func (s Slot) value(opts ScheduleOptions) float64 {
+	if opts.Pessimistic && s.Band != nil {
+		return s.depleted(s.Band.P10)
+	}
+	return s.depleted(s.Capacity)
+}
+
+// depleted subtracts the slot's depletion from a capacity.
+func (s Slot) depleted(capacity float64) float64 {
+	return math.Max(0, capacity-s.Depletion)
+}
+
+// allows reports whether the slot may hold a task of the given effort under the band floor.
+func (s Slot) allows(effort int, opts ScheduleOptions) bool {
+	floor := opts.BandFloor
+	if floor == nil || s.Band == nil || effort < floor.Effort {
+		return true
+	}
+	return s.depleted(s.Band.P10) >= floor.P10
+}
+
+// averageBand averages the (depleted) bands of consecutive slots (nil if they have none).
+func averageBand(slots []Slot, weights []float64) *CapacityBand {
+	if len(slots) == 0 || slots[0].Band == nil {
+		return nil
+	}
+	var avg CapacityBand
+	for i, s := range slots {
+		avg.Mean += weights[i] * s.depleted(s.Band.Mean)
+		avg.P10 += weights[i] * s.depleted(s.Band.P10)
+		avg.P90 += weights[i] * s.depleted(s.Band.P90)
+	}
+	return &avg
+}
+
+// slotWeights spreads a task over the n slots it occupies in proportion to the time it spends in
+// each. They add up to 1, so a weighted sum is the average over the task itself; a last slot the
+// task only partly uses counts for less.
+func slotWeights(length time.Duration, n int, opts ScheduleOptions) []float64 {
+	weights := make([]float64, n)
+	remaining := length
+	for i := range weights {
+		used := remaining
+		if used > opts.slotLength() {
+			used = opts.slotLength()
+		}
+		weights[i] = float64(used) / float64(length)
+		remaining -= used
+	}
+	return weights
+}
+
+// allocate books the (already sorted) tasks into the slots, marking them booked as it goes.
+// A task is only considered once everything it depends on is settled, and then only starts
+// after its dependencies finish; if one of them could not be booked, it is Blocked.
+// Splittable tasks are booked chunk by chunk, and with a BreakPolicy every hard block is
+// followed by a "Break" item.
+// The score adds up effort * duration * capacity over every booked task, so it rewards both
+// fitting tasks in and putting the hard ones where capacity is high.
+// Budget caps the minutes of high-effort work booked per day (-1 for no cap).
+// Every booking drains capacity from the slots after it, so slot capacities are re-evaluated
+// before the next task is placed: hard blocks get spread out instead of stacked.
+func allocate(tasks []Task, slots []Slot, budget int, opts ScheduleOptions) ([]ScheduleItem, float64) {
+	var schedule []placement
+	var load workload
+	total := 0.0
+	spent := make(map[string]int) // High-effort minutes booked per day; the budget is daily
+	deps := newDependencies()
+	pending := append([]Task(nil), tasks...)
+
+	// Fixed events are on the calendar before any task, and their effort already tires the user
+	schedule = placeEvents(slots, opts.Events, opts, &load)
+	load.deplete(slots)
+
+	// The Allocation Loop: always the highest-priority task whose dependencies are settled
+	for len(pending) > 0 {
+		var task Task
+		task, pending = nextReady(pending, deps)
+
+		if dep := deps.blockedBy(task); dep != "" {
+			schedule = append(schedule, placement{ScheduleItem: ScheduleItem{
+				StartTime: "UNSCHEDULED",
+				TaskID:    task.ID,
+				TaskName:  task.Name,
+				FitScore:  BlockedFitScore,
+				Reason:    fmt.Sprintf("depends on %q, which could not be scheduled", dep),
+			}})
+			deps.settle(task, time.Time{}, false)
+			continue
+		}
+		after, lastDep := deps.notBefore(task)
+
+		// A split task can spread its hard work over several days' budgets
+		rationed := budget >= 0 && task.Effort >= highEffortLevel
+		if rationed && task.Duration > budget && !task.Splittable {
+			schedule = append(schedule, placement{ScheduleItem: ScheduleItem{
+				StartTime: "UNSCHEDULED",
+				TaskID:    task.ID,
+				TaskName:  task.Name,
+				FitScore:  "Sleep Debt",
+				Reason:    fmt.Sprintf("sleep debt limits effort-%d+ work to %d minutes a day", highEffortLevel, budget),
+			}})
+			deps.settle(task, time.Time{}, false)
+			continue
+		}
+
+		// Book the task (or its chunks, best first) on copies, and keep them only if all of it fits
+		trial := append([]Slot(nil), slots...)
+		trialLoad := append(workload(nil), load...)
+		trialSpent := maps.Clone(spent)
+		var blocks, breaks []placement
+		var parts []TimeBlock
+		var found candidate // Only the flags, accumulated over every search
+		score := 0.0
+		remaining := task.Duration
+		for remaining > 0 {
+			best := candidate{start: -1}
+			var piece Task
+			for _, minutes := range task.chunkLengths(remaining, opts) {
+				piece = task.chunk(minutes)
+				best = search(piece, trial, after, parts, trialLoad, trialSpent, budget, opts)
+				found.allowed = found.allowed || best.allowed
+				found.ready = found.ready || best.ready
+				found.overBudget = found.overBudget || best.overBudget
+				if best.start >= 0 {
 					break
 				}
-				avgCap += slots[i+j].Capacity
 			}
-			avgCap /= float64(slotsNeeded)
+			if best.start < 0 {
+				break
+			}
+
+			item, rest, block := book(piece, best, trial, &trialLoad, opts)
+			blocks = append(blocks, item)
+			if rest != nil {
+				breaks = append(breaks, *rest)
+			}
+			parts = append(parts, block)
+			score += float64(task.Effort) * (time.Duration(piece.Duration) * time.Minute).Hours() * best.score
+			if rationed {
+				trialSpent[slotDay(trial[best.start])] += piece.Duration
+			}
+			remaining -= piece.Duration
+		}
 
-			if available {
-				// Simple scoring: Higher capacity is better for hard tasks
-				if avgCap > bestScore {
-					bestScore = avgCap
-					bestStartIdx = i
+		if remaining == 0 {
+			copy(slots, trial)
+			load, spent = trialLoad, trialSpent
+			total += score
+
+			// Number the focus blocks in time order. The task is done when the last one ends.
+			sort.SliceStable(blocks, func(i, j int) bool {
+				ti, _ := blocks[i].start()
+				tj, _ := blocks[j].start()
+				return ti.Before(tj)
+			})
+			if len(blocks) > 1 {
+				for i := range blocks {
+					blocks[i].Part, blocks[i].Parts = i+1, len(blocks)
 				}
 			}
+			last := blocks[len(blocks)-1]
+			lastStart, _ := last.start()
+			deps.settle(task, lastStart.Add(time.Duration(last.Duration)*time.Minute), true)
+			schedule = append(append(schedule, blocks...), breaks...)
+			continue
+		}
+
+		// Handle un-bookable task (e.g., day is full), and say why
+		minutes := task.Duration
+		if task.Splittable {
+			minutes, _ = task.chunkBounds()
+			minutes = min(minutes, task.Duration)
+		}
+		item := ScheduleItem{StartTime: "UNSCHEDULED", TaskID: task.ID, TaskName: task.Name}
+		switch {
+		case !found.allowed:
+			item.FitScore = InfeasibleFitScore
+			item.Reason = task.chunk(minutes).infeasibility(slots[0].Time, slots[len(slots)-1].Time.Add(opts.slotLength()))
+		case !found.ready:
+			item.FitScore = BlockedFitScore
+			item.Reason = fmt.Sprintf("must start after %q finishes at %s, which leaves no start its constraints allow",
+				lastDep, after.Format("Mon 15:04"))
+		case found.overBudget:
+			item.FitScore = "Sleep Debt"
+			item.Reason = fmt.Sprintf("sleep debt limits effort-%d+ work to %d minutes a day, and the free days are used up", highEffortLevel, budget)
+		case len(blocks) > 0:
+			item.FitScore = "No Time/Energy"
+			item.Reason = fmt.Sprintf("only %d of its %d minutes fit in free focus blocks", task.Duration-remaining, task.Duration)
+		case task.constrained():
+			item.FitScore = "No Time/Energy"
+			item.Reason = "every start its constraints allow is booked, asleep or below the band floor"
+		default:
+			stretch := fmt.Sprintf("no free %d-minute stretch", minutes)
+			if opts.Breaks.after(task) {
+				stretch += fmt.Sprintf(" followed by a %d-minute break", opts.Breaks.Minutes)
+			}
+			item.FitScore = "No Time/Energy"
+			item.Reason = stretch + " in the planning horizon"
+			if lastDep != "" {
+				item.Reason = fmt.Sprintf("%s after %q finishes", stretch, lastDep)
+			}
+		}
+		schedule = append(schedule, placement{ScheduleItem: item})
+		deps.settle(task, time.Time{}, false)
+	}
+
+	return finalize(schedule, slots, load), total
+}
+
+// placement is a schedule item together with the slots it took, so its capacity can be read
+// again once the whole plan is booked.
+type placement struct {
+	ScheduleItem
+	effort  int       // The task's effort, for judging its fit; 0 keeps FitScore (events, breaks)
+	slots   []int     // Indexes into the slots; none for UNSCHEDULED items
+	weights []float64 // How much of the item each of those slots holds
+}
+
+// finalize re-reads every placed item's capacity from the finished workload. An item keeps the
+// depletion it was booked with only until something is booked before it: a hard block placed
+// later in the morning still tires out the task booked after it, and the item has to say so.
+// Its fit is judged on the capacity it reports, not the drain-adjusted score that ranked it.
+func finalize(schedule []placement, slots []Slot, load workload) []ScheduleItem {
+	items := make([]ScheduleItem, len(schedule))
+	for i, p := range schedule {
+		items[i] = p.ScheduleItem
+		if len(p.slots) == 0 {
+			continue
+		}
+
+		covered := make([]Slot, len(p.slots))
+		for j, k := range p.slots {
+			covered[j] = slots[k]
+			covered[j].Depletion = load.depletion(covered[j].Time)
+		}
+		items[i].PredictedCap = averageCapacity(covered, p.weights)
+		if items[i].Interval != nil {
+			items[i].Interval = averageBand(covered, p.weights)
+		}
+		if items[i].Performance != nil {
+			performance := PerformanceAt(items[i].PredictedCap)
+			items[i].Performance = &performance
+		}
+		if p.effort > 0 {
+			items[i].FitScore = judgeFit(p.effort, items[i].PredictedCap)
+		}
+	}
+	return items
+}
+
+// candidate is the best start search found for a block, and what ruled out the others.
+type candidate struct {
+	start      int     // Slot index, -1 if nothing fits
+	score      float64 // Average capacity over the block, net of the drain on later work
+	rest       []int   // Slots of the break that must follow the block
+	allowed    bool    // Some start meets the task's own constraints, booked or not
+	ready      bool    // ... and comes after its dependencies finish
+	overBudget bool    // A free window was turned down only because its day's budget is used up
+}
+
+// search finds the sequence of free slots that maximizes capacity for the task (a whole task or
+// one chunk of one): High Capacity for High Effort. The start must meet the task's constraints,
+// come after 'after', not touch the task's other parts, stay within the day's budget and leave
+// room for the break that has to follow.
+func search(task Task, slots []Slot, after time.Time, parts []TimeBlock, load workload, spent map[string]int, budget int, opts ScheduleOptions) candidate {
+	length := time.Duration(task.Duration) * time.Minute
+	slotsNeeded := opts.slotsFor(length)
+	weights := slotWeights(length, slotsNeeded, opts)
+	rationed := budget >= 0 && task.Effort >= highEffortLevel
+	best := candidate{start: -1, score: -1.0}
+
+	for i := 0; i <= len(slots)-slotsNeeded; i++ {
+		if !task.fits(slots[i].Time) {
+			continue
+		}
+		best.allowed = true
+		if slots[i].Time.Before(after) {
+			continue
+		}
+		best.ready = true
+		if adjoins(parts, slots[i].Time, slots[i].Time.Add(time.Duration(slotsNeeded)*opts.slotLength())) {
+			continue
+		}
+
+		// Check if slots are free
+		available := true
+		avgCap := 0.0
+		for j := 0; j < slotsNeeded; j++ {
+			if slots[i+j].IsBooked || !slots[i+j].allows(task.Effort, opts) {
+				available = false
+				break
+			}
+			avgCap += weights[j] * slots[i+j].value(opts)
+		}
+		if available && rationed && spent[slotDay(slots[i])]+task.Duration > budget {
+			available = false
+			best.overBudget = true
+		}
+		if !available {
+			continue
+		}
+		rest, ok := breakSlots(task, slots, i+slotsNeeded, opts)
+		if !ok {
+			continue
+		}
+		avgCap -= load.drain(newBooking(task, slots[i].Time))
+
+		// Simple scoring: Higher capacity is better for hard tasks
+		if avgCap > best.score {
+			best.start, best.score, best.rest = i, avgCap, rest
+		}
+	}
+	return best
+}
+
+// book marks the candidate's slots booked and returns the schedule item, the break after it (if
+// any) and the block of slots it took. The free slots are re-evaluated now that this block will
+// tire the user out; booked slots are brought up to date by finalize.
+func book(task Task, c candidate, slots []Slot, load *workload, opts ScheduleOptions) (placement, *placement, TimeBlock) {
+	length := time.Duration(task.Duration) * time.Minute
+	slotsNeeded := opts.slotsFor(length)
+	weights := slotWeights(length, slotsNeeded, opts)
+
+	booked := slots[c.start : c.start+slotsNeeded]
+	for j := range booked {
+		booked[j].IsBooked = true
+	}
+	capacity := averageCapacity(booked, weights)
+	performance := PerformanceAt(capacity)
+	item := placement{
+		ScheduleItem: ScheduleItem{
+			StartTime:    booked[0].Time.Format(time.RFC3339),
+			TaskID:       task.ID,
+			TaskName:     task.Name,
+			Duration:     task.Duration,
+			PredictedCap: capacity,
+			Interval:     averageBand(booked, weights),
+			Performance:  &performance,
+			FitScore:     judgeFit(task.Effort, capacity),
+		},
+		effort:  task.Effort,
+		slots:   indexes(c.start, slotsNeeded),
+		weights: weights,
+	}
+	rest := breakItem(c.rest, slots, opts)
+
+	*load = append(*load, newBooking(task, booked[0].Time))
+	load.deplete(slots)
+	return item, rest, TimeBlock{Start: booked[0].Time, End: booked[0].Time.Add(time.Duration(slotsNeeded) * opts.slotLength())}
+}
+
+// napSlots returns a copy of the nap-free slots as the napped model sees them, from the nap's
+// slot i on. Only the slots up to the end of the next night are sampled again: a night's sleep
+// all but wipes out the pressure a nap took off, and re-reading a multi-day horizon for every
+// nap candidate made long plans take seconds.
+func napSlots(free []Slot, i int, nap SleepEpisode, napped CapacityModel, opts ScheduleOptions) []Slot {
+	slots := append([]Slot(nil), free...)
+	end := len(slots)
+	if night, ok := napped.Biology().nextNight(nap.End); ok {
+		for k := i; k < len(slots); k++ {
+			if !slots[k].Time.Before(night.End) {
+				end = k
+				break
+			}
 		}
+	}
+	sampleSlots(slots[i:end], napped, opts)
+	return slots
+}
+
+// scheduleWithNap re-plans the day with a nap in every free position and returns the best plan,
+// if it beats the nap-free score. The nap occupies its slots, and the model sees it as sleep:
+// pressure drops, followed by a little inertia. Like RecommendNap, it keeps naps napAwakeGap
+// away from the nights.
+func scheduleWithNap(tasks []Task, startHour time.Time, model CapacityModel, budget int, opts ScheduleOptions) ([]ScheduleItem, bool) {
+	params := model.Biology().anchored()
+	model = model.WithBiology(params)
+
+	free := buildSlots(model, startHour, opts)
+	_, baseline := allocate(tasks, append([]Slot(nil), free...), budget, opts)
+
+	var best []ScheduleItem
+	bestScore := baseline * (1 + napMinimumGain)
 
-		// 4. Book the slots
-		if bestStartIdx != -1 {
+	// Naps start napSearchStep apart, as in RecommendNap: finer slots only multiply the re-plans
+	stride := opts.slotsFor(napSearchStep)
+	for i := 0; i < len(free); i += stride {
+		if free[i].Time.Sub(free[0].Time) >= napSearchWindow {
+			break
+		}
+		for _, length := range scheduleNapLengths {
+			slotsNeeded := opts.slotsFor(length)
+			if i+slotsNeeded > len(free) || !slotsFree(free[i:i+slotsNeeded]) {
+				continue
+			}
+
+			nap := SleepEpisode{Start: free[i].Time, End: free[i].Time.Add(length), Planned: true, Nap: true}
+			if params.Sleep.crowds(nap) {
+				continue
+			}
+			slots := napSlots(free, i, nap, model.WithBiology(params.withNap(nap)), opts)
 			for j := 0; j < slotsNeeded; j++ {
-				slots[bestStartIdx+j].IsBooked = true
+				slots[i+j].IsBooked = true
 			}
 
-			// Add to schedule
-			schedule = append(schedule, ScheduleItem{
-				StartTime:    slots[bestStartIdx].Time.Format("15:04"),
-				TaskName:     task.Name,
-				PredictedCap: bestScore,
-				FitScore:     judgeFit(task.Effort, bestScore),
-			})
-		} else {
-			// Handle un-bookable task (e.g., day is full)
-			schedule = append(schedule, ScheduleItem{
-				StartTime: "UNSCHEDULED",
-				TaskName:  task.Name,
-				FitScore:  "No Time/Energy",
-			})
+			schedule, score := allocate(tasks, slots, budget, opts)
+			if score > bestScore {
+				bestScore = score
+				best = append(schedule, ScheduleItem{
+					StartTime:    nap.Start.Format(time.RFC3339),
+					TaskName:     NapTaskName,
+					Duration:     int(length.Minutes()),
+					PredictedCap: free[i].Capacity,
+					FitScore:     fmt.Sprintf("Recharge (%d min)", int(length.Minutes())),
+				})
+			}
 		}
 	}
+	return best, best != nil
+}
 
-	// Sort schedule by time for readability
-	sort.Slice(schedule, func(i, j int) bool {
-		return schedule[i].StartTime < schedule[j].StartTime
-	})
+// start parses the item's StartTime. It reports false for UNSCHEDULED items.
+func (item ScheduleItem) start() (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339, item.StartTime)
+	return t, err == nil
+}
 
-	return schedule
+// indexes returns the n slot indexes from start.
+func indexes(start, n int) []int {
+	idx := make([]int, n)
+	for j := range idx {
+		idx[j] = start + j
+	}
+	return idx
+}
+
+// averageCapacity is the weighted mean central (depleted) capacity over consecutive slots.
+func averageCapacity(slots []Slot, weights []float64) float64 {
+	total := 0.0
+	for i, s := range slots {
+		total += weights[i] * s.depleted(s.Capacity)
+	}
+	return total
+}
+
+// slotDay is the calendar day (in the user's zone) a slot falls on.
+func slotDay(s Slot) string {
+	return s.Time.Format("2006-01-02")
+}
+
+// containsInt reports whether values holds v.
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// slotsFree reports whether none of the slots is booked.
+func slotsFree(slots []Slot) bool {
+	for _, s := range slots {
+		if s.IsBooked {
+			return false
+		}
+	}
+	return true
 }
 
 func judgeFit(effort int, capacity float64) string {
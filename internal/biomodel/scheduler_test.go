@@ -0,0 +1,193 @@
+package biomodel
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+// flatSlots is a run of free default-length slots from start with the given capacities.
+func flatSlots(start time.Time, capacities ...float64) []Slot {
+	slots := make([]Slot, len(capacities))
+	for i, c := range capacities {
+		slots[i] = Slot{Time: start.Add(time.Duration(i) * DefaultSlotMinutes * time.Minute), Capacity: c}
+	}
+	return slots
+}
+
+func TestAllocateRereadsCapacityOfEarlierBookings(t *testing.T) {
+	start := time.Date(2026, 3, 10, 8, 0, 0, 0, time.UTC)
+
+	// The peak at 09:00 goes to the first task. The second can only go before it (10:00 on is
+	// poor), so it is booked later but runs first, and the first task runs tired.
+	slots := flatSlots(start, 0.8, 0.8, 0.95, 0.95, 0.6, 0.6, 0.6, 0.6)
+	tasks := []Task{
+		{Name: "peak", Duration: 60, Effort: 10},
+		{Name: "early", Duration: 60, Effort: 10},
+	}
+
+	schedule, _ := allocate(tasks, slots, -1, ScheduleOptions{})
+	byName := make(map[string]ScheduleItem)
+	for _, item := range schedule {
+		byName[item.TaskName] = item
+	}
+	peak, early := byName["peak"], byName["early"]
+	if peak.StartTime != "2026-03-10T09:00:00Z" || early.StartTime != "2026-03-10T08:00:00Z" {
+		t.Fatalf("test setup: want peak at 09:00 and early at 08:00, got %s and %s", peak.StartTime, early.StartTime)
+	}
+
+	if peak.PredictedCap >= 0.95-0.05 {
+		t.Errorf("peak runs right after an hour of effort-10 work but kept capacity %.3f", peak.PredictedCap)
+	}
+	if got := PerformanceAt(peak.PredictedCap); *peak.Performance != got {
+		t.Errorf("peak's performance %+v is not the one at its capacity, %+v", *peak.Performance, got)
+	}
+
+	// Ranked, early lost the drain it puts on peak; judged, it runs at a plain 0.8
+	if early.PredictedCap != 0.8 {
+		t.Errorf("early has nothing before it, want capacity 0.8, got %.3f", early.PredictedCap)
+	}
+	if want := judgeFit(10, 0.8); early.FitScore != want {
+		t.Errorf("early's fit is %q, want %q for the capacity it reports", early.FitScore, want)
+	}
+}
+
+func TestNapSlotsResampleUpToTheNextNight(t *testing.T) {
+	start := time.Date(2026, 3, 10, 8, 0, 0, 0, time.UTC)
+	sleep, _ := DefaultRoutine.Nights(start.Add(-time.Hour), 1)
+	opts := ScheduleOptions{HorizonHours: 48}
+	model := withNights(AverageModel{Params: BioParams{Sleep: sleep}}, start, start.Add(opts.horizon()))
+
+	free := buildSlots(model, start, opts)
+	i := 10 // 13:00
+	nap := SleepEpisode{Start: free[i].Time, End: free[i].Time.Add(20 * time.Minute), Planned: true, Nap: true}
+	napped := model.WithBiology(model.Biology().withNap(nap))
+	night, ok := napped.Biology().nextNight(nap.End)
+	if !ok {
+		t.Fatal("test setup: want a night in the horizon")
+	}
+
+	got := napSlots(free, i, nap, napped, opts)
+	want := buildSlots(napped, start, opts)
+	for k := range got {
+		switch {
+		case k >= i && got[k].Time.Before(night.End):
+			if got[k] != want[k] {
+				t.Fatalf("slot %s: got %+v, want the napped model's %+v", got[k].Time.Format("Mon 15:04"), got[k], want[k])
+			}
+		case got[k] != free[k]:
+			t.Fatalf("slot %s is outside the nap's reach but changed: got %+v, want %+v", got[k].Time.Format("Mon 15:04"), got[k], free[k])
+		}
+	}
+}
+
+// BenchmarkOptimizeScheduleWeekWithNap plans a week in 5-minute slots, trying a nap in every
+// position of the first day.
+func BenchmarkOptimizeScheduleWeekWithNap(b *testing.B) {
+	start := time.Date(2026, 3, 10, 8, 0, 0, 0, time.UTC)
+	sleep, _ := DefaultRoutine.Nights(start.Add(-time.Hour), 1)
+	model := AverageModel{Params: BioParams{Sleep: sleep}}
+	var tasks []Task
+	for i := 0; i < 12; i++ {
+		tasks = append(tasks, Task{Name: fmt.Sprintf("task %d", i), Duration: 90, Effort: 3 + i%7})
+	}
+	opts := ScheduleOptions{AllowNap: true, SlotMinutes: 5, HorizonHours: 168}
+
+	for i := 0; i < b.N; i++ {
+		if _, err := OptimizeSchedule(append([]Task(nil), tasks...), start, model, opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestSleepLogCrowds(t *testing.T) {
+	night := SleepEpisode{Start: time.Date(2026, 3, 9, 23, 0, 0, 0, time.UTC), End: time.Date(2026, 3, 10, 7, 0, 0, 0, time.UTC)}
+	log := SleepLog{night}
+	tests := []struct {
+		start  time.Time
+		crowds bool
+	}{
+		{night.End, true}, // Sleeping in
+		{night.End.Add(napAwakeGap - time.Minute), true}, // Not yet awake long enough
+		{night.End.Add(napAwakeGap), false},
+		{night.Start.Add(-napAwakeGap - 20*time.Minute), false},
+		{night.Start.Add(-20 * time.Minute), true}, // Turning in early
+	}
+	for _, tt := range tests {
+		nap := SleepEpisode{Start: tt.start, End: tt.start.Add(20 * time.Minute), Nap: true}
+		if got := log.crowds(nap); got != tt.crowds {
+			t.Errorf("nap at %s: crowds = %v, want %v", tt.start.Format("Mon 15:04"), got, tt.crowds)
+		}
+	}
+}
+
+func TestOptimizeScheduleKeepsNapsAwayFromNights(t *testing.T) {
+	start := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	sleep, _ := DefaultRoutine.Nights(start.Add(-time.Hour), 1)
+	model := AverageModel{Params: BioParams{Sleep: sleep}}
+	var tasks []Task
+	for i := 0; i < 12; i++ {
+		tasks = append(tasks, Task{Name: fmt.Sprintf("task %d", i), Duration: 90, Effort: 3 + i%7})
+	}
+	opts := ScheduleOptions{AllowNap: true, HorizonHours: 48}
+
+	schedule, err := OptimizeSchedule(tasks, start, model, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nights := withNights(model, start, start.Add(opts.horizon())).Biology().Sleep
+	for _, item := range schedule {
+		begin, ok := item.start()
+		if item.TaskName != NapTaskName || !ok {
+			continue
+		}
+		nap := SleepEpisode{Start: begin, End: begin.Add(time.Duration(item.Duration) * time.Minute), Nap: true}
+		if nights.crowds(nap) {
+			t.Errorf("nap at %s is within %v of a night", begin.Format("Mon 15:04"), napAwakeGap)
+		}
+	}
+}
+
+func TestShortTasksUnderEverySlotSize(t *testing.T) {
+	start := time.Date(2026, 3, 10, 8, 0, 0, 0, time.UTC)
+	sleep, _ := DefaultRoutine.Nights(start.Add(-time.Hour), 1)
+	model := AverageModel{Params: BioParams{Sleep: sleep}}
+
+	for _, size := range append([]int{0}, SlotSizes...) {
+		opts := ScheduleOptions{SlotMinutes: size}
+		if err := opts.Validate(); err != nil {
+			t.Errorf("slot_minutes %d: %v", size, err)
+			continue
+		}
+		for _, minutes := range []int{15, 45} {
+			slot := opts.slotLength()
+			want := int(math.Ceil(float64(minutes) / slot.Minutes()))
+			if got := opts.slotsFor(time.Duration(minutes) * time.Minute); got != want {
+				t.Errorf("%v slots: a %d-minute task takes %d slots, want %d", slot, minutes, got, want)
+			}
+
+			tasks := []Task{{Name: "short", Duration: minutes, Effort: 6}}
+			schedule, err := OptimizeSchedule(tasks, start, model, opts)
+			if err != nil {
+				t.Fatalf("%v slots, %d minutes: %v", slot, minutes, err)
+			}
+			if len(schedule) != 1 {
+				t.Fatalf("%v slots, %d minutes: want one item, got %+v", slot, minutes, schedule)
+			}
+			item := schedule[0]
+			if item.Duration != minutes {
+				t.Errorf("%v slots: a %d-minute task is scheduled for %d minutes", slot, minutes, item.Duration)
+			}
+			if math.IsNaN(item.PredictedCap) || item.PredictedCap <= 0 || item.PredictedCap > 1 {
+				t.Errorf("%v slots, %d minutes: predicted capacity %v", slot, minutes, item.PredictedCap)
+			}
+		}
+	}
+
+	for _, size := range []int{-5, 1, 7, 20, 45, 60} {
+		if err := (ScheduleOptions{SlotMinutes: size}).Validate(); err == nil {
+			t.Errorf("slot_minutes %d was accepted", size)
+		}
+	}
+}
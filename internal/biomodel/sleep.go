@@ -0,0 +1,180 @@
+package biomodel
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Homeostatic defaults. The recovery constant and asymptotes follow
+// Daan, Beersma & Borbély (1984); the rise constant keeps TardiGo's historical 16h.
+const (
+	DefaultFatigueRate    = 16.0 // Hours. Time constant of S rising while awake.
+	DefaultRecoveryRate   = 4.2  // Hours. Time constant of S decaying while asleep.
+	DefaultUpperAsymptote = 1.0  // S approaches this value with extended wakefulness.
+	DefaultLowerAsymptote = 0.0  // S approaches this value with extended sleep.
+
+	// Sleep inertia (Process W). Åkerstedt's W starts at roughly half the S range and fades within ~90 min.
+	DefaultInertiaDecay = 0.5 // Hours. Time constant of grogginess fading after waking.
+	inertiaWeight       = 0.5 // Capacity lost at the moment of waking (W = 1.0).
+
+	// assumedPriorWake is how long we assume the user was awake before the first logged bedtime.
+	// We have no data before it, so we pretend it followed a normal day starting fully rested.
+	assumedPriorWake = 16 * time.Hour
+)
+
+// SleepEpisode is a single block of sleep, from lights-out to wake-up.
+type SleepEpisode struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Planned bool      `json:"planned,omitempty"` // True for future nights we expect rather than observed
+	Nap     bool      `json:"nap,omitempty"`     // A daytime nap rather than the main night
+}
+
+// Contains reports whether t falls inside the episode.
+func (e SleepEpisode) Contains(t time.Time) bool {
+	return !t.Before(e.Start) && t.Before(e.End)
+}
+
+// fatigueRate returns the rise time constant, falling back to the default when unset.
+func (b *BioParams) fatigueRate() float64 {
+	if b.FatigueRate <= 0 {
+		return DefaultFatigueRate
+	}
+	return b.FatigueRate
+}
+
+// recoveryRate returns the decay time constant, falling back to the default when unset.
+func (b *BioParams) recoveryRate() float64 {
+	if b.RecoveryRate <= 0 {
+		return DefaultRecoveryRate
+	}
+	return b.RecoveryRate
+}
+
+// asymptotes returns the (upper, lower) bounds of Process S.
+// An unset or inverted pair falls back to the defaults.
+func (b *BioParams) asymptotes() (float64, float64) {
+	if b.UpperAsymptote <= b.LowerAsymptote {
+		return DefaultUpperAsymptote, DefaultLowerAsymptote
+	}
+	return b.UpperAsymptote, b.LowerAsymptote
+}
+
+// sleepFloor is the level S decays toward while asleep at t: the lower asymptote, raised by the
+// sleep debt carried at t.
+func (b *BioParams) sleepFloor(t time.Time) float64 {
+	upper, lower := b.asymptotes()
+	return lower + debtFloor(b.SleepDebt(t), upper, lower)
+}
+
+// sortedSleep returns the sleep episodes in chronological order, dropping empty ones.
+func (b *BioParams) sortedSleep() []SleepEpisode {
+	episodes := make([]SleepEpisode, 0, len(b.Sleep))
+	for _, ep := range b.Sleep {
+		if ep.End.After(ep.Start) {
+			episodes = append(episodes, ep)
+		}
+	}
+	sort.Slice(episodes, func(i, j int) bool {
+		return episodes[i].Start.Before(episodes[j].Start)
+	})
+	return episodes
+}
+
+// sleepPressure integrates Process S from the first known point up to targetTime.
+// It returns the raw pressure (between the asymptotes) and whether the user is asleep at targetTime.
+// Chronic sleep debt raises the floor S decays toward, so short nights never fully clear it.
+//
+//	Awake:  S(t) = UA - (UA - S0) * e^(-t / FatigueRate)
+//	Asleep: S(t) = LA + (S0 - LA) * e^(-t / RecoveryRate)
+func (b *BioParams) sleepPressure(targetTime time.Time) (float64, bool) {
+	upper, lower := b.asymptotes()
+	tauRise, tauDecay := b.fatigueRate(), b.recoveryRate()
+	floor := b.sleepFloor(targetTime)
+
+	episodes := b.sortedSleep()
+
+	// Without a sleep history we fall back to the single WakeTime anchor:
+	// the user is fully rested at WakeTime and has been awake ever since.
+	if len(episodes) == 0 {
+		hoursAwake := targetTime.Sub(b.WakeTime).Hours()
+		if hoursAwake < 0 {
+			hoursAwake = 0 // Handle edge case if checking time before wake
+		}
+		return rise(lower, upper, hoursAwake, tauRise), false
+	}
+
+	s := lower
+	cursor := episodes[0].Start.Add(-assumedPriorWake)
+
+	for _, ep := range episodes {
+		if !targetTime.After(ep.Start) {
+			break
+		}
+		if !ep.End.After(cursor) {
+			continue // Fully overlapped by an earlier episode
+		}
+
+		// 1. Awake stretch leading up to this episode
+		if ep.Start.After(cursor) {
+			s = rise(s, upper, ep.Start.Sub(cursor).Hours(), tauRise)
+			cursor = ep.Start
+		}
+
+		// 2. The episode itself (possibly cut short by targetTime)
+		if targetTime.Before(ep.End) {
+			return decay(s, floor, targetTime.Sub(cursor).Hours(), tauDecay), true
+		}
+		s = decay(s, floor, ep.End.Sub(cursor).Hours(), tauDecay)
+		cursor = ep.End
+	}
+
+	// 3. Awake since the last episode
+	if targetTime.After(cursor) {
+		s = rise(s, upper, targetTime.Sub(cursor).Hours(), tauRise)
+	}
+	return s, false
+}
+
+// sleepInertia computes Process W: 1.0 at the moment of waking from a night, decaying exponentially
+// to 0.0. Naps start lower, depending on the sleep stage they end in (see napInertia).
+//
+//	W(t) = W0 * e^(-t / InertiaDecay)
+func (b *BioParams) sleepInertia(targetTime time.Time) float64 {
+	last, ok := b.lastEpisode(targetTime)
+	if !ok {
+		return 0
+	}
+	tau := b.InertiaDecay
+	if tau <= 0 {
+		tau = DefaultInertiaDecay
+	}
+	initial := 1.0
+	if last.Nap {
+		initial = napInertia(last.End.Sub(last.Start))
+	}
+	return initial * math.Exp(-targetTime.Sub(last.End).Hours()/tau)
+}
+
+// lastEpisode returns the most recent sleep that ended at or before targetTime.
+// Without a sleep log, WakeTime stands in for the end of last night.
+func (b *BioParams) lastEpisode(targetTime time.Time) (SleepEpisode, bool) {
+	if len(b.Sleep) > 0 {
+		return b.Sleep.lastEpisode(targetTime)
+	}
+	if b.WakeTime.IsZero() || targetTime.Before(b.WakeTime) {
+		return SleepEpisode{}, false
+	}
+	return SleepEpisode{Start: b.WakeTime, End: b.WakeTime}, true
+}
+
+// rise moves S from s0 toward the upper asymptote over the given hours of wakefulness.
+func rise(s0, upper, hours, tau float64) float64 {
+	return upper - (upper-s0)*math.Exp(-hours/tau)
+}
+
+// decay moves S from s0 toward the lower asymptote over the given hours of sleep.
+func decay(s0, lower, hours, tau float64) float64 {
+	return lower + (s0-lower)*math.Exp(-hours/tau)
+}
@@ -0,0 +1,104 @@
+package biomodel
+
+import (
+	"fmt"
+	"time"
+)
+
+// Sleep-gate thresholds of the two-process model, on the normalized S scale (0 = lower asymptote,
+// 1 = upper asymptote). Sleep starts when S climbs to the upper threshold and ends when it falls
+// to the lower one. Both thresholds follow Process C, so sleep is easier to start at the circadian
+// trough and harder to keep up as the morning rise begins.
+// Calibrated so that the default BioParams settle into the 23:00 -> 07:00 routine.
+const (
+	onsetLevel         = 0.77
+	wakeLevel          = 0.08
+	thresholdAmplitude = 0.10
+
+	adviceStep        = 5 * time.Minute
+	maxPredictedWake  = 36 * time.Hour // Give up if S never reaches the onset threshold
+	maxPredictedSleep = 18 * time.Hour // Give up if S never falls to the wake threshold
+)
+
+// SleepWindow is a predicted night: when pressure forces sleep, and when it has dissipated enough to wake.
+type SleepWindow struct {
+	Bedtime  time.Time `json:"bedtime"`
+	WakeTime time.Time `json:"wake_time"`
+}
+
+// Duration returns how long the predicted night lasts.
+func (w SleepWindow) Duration() time.Duration {
+	return w.WakeTime.Sub(w.Bedtime)
+}
+
+// PredictSleepWindow projects Process S forward from 'from' (assuming the user stays awake)
+// and returns the next natural night implied by the thresholds. During the night S decays toward
+// the same debt-raised floor sleepPressure uses, so a short-slept user is predicted to need longer.
+func PredictSleepWindow(params BioParams, from time.Time) (SleepWindow, error) {
+	upper, lower := params.asymptotes()
+	tauRise, tauDecay := params.fatigueRate(), params.recoveryRate()
+
+	s, _ := params.sleepPressure(from)
+
+	// 1. Stay awake until pressure crosses the (circadian-modulated) onset threshold
+	t := from
+	for !t.After(from.Add(maxPredictedWake)) {
+		if normalize(s, upper, lower) >= params.onsetThreshold(t) {
+			break
+		}
+		s = rise(s, upper, adviceStep.Hours(), tauRise)
+		t = t.Add(adviceStep)
+	}
+	if t.After(from.Add(maxPredictedWake)) {
+		return SleepWindow{}, fmt.Errorf("sleep pressure never reaches the onset threshold within %v", maxPredictedWake)
+	}
+	bedtime := t
+	floor := params.sleepFloor(bedtime)
+
+	// 2. Sleep until pressure falls to the wake threshold
+	for !t.After(bedtime.Add(maxPredictedSleep)) {
+		if normalize(s, upper, lower) <= params.wakeThreshold(t) {
+			return SleepWindow{Bedtime: bedtime, WakeTime: t}, nil
+		}
+		s = decay(s, floor, adviceStep.Hours(), tauDecay)
+		t = t.Add(adviceStep)
+	}
+	return SleepWindow{}, fmt.Errorf("sleep pressure never falls to the wake threshold within %v", maxPredictedSleep)
+}
+
+// OptimalBedtime returns the latest bedtime after 'from' that still lets sleep pressure dissipate
+// to the wake threshold by targetWake. If even going to bed at 'from' is not enough, it returns
+// 'from' and false: the user should sleep as soon as possible and expect to wake unrested.
+// With sleep debt the floor S decays toward is raised (as in sleepPressure), so bedtimes move earlier.
+func OptimalBedtime(params BioParams, from, targetWake time.Time) (time.Time, bool) {
+	upper, lower := params.asymptotes()
+	tauRise, tauDecay := params.fatigueRate(), params.recoveryRate()
+
+	sNow, _ := params.sleepPressure(from)
+	goal := params.wakeThreshold(targetWake)
+
+	// Walk backwards from the target wake time: the first bedtime that works is the latest one.
+	for bed := targetWake.Add(-adviceStep); bed.After(from); bed = bed.Add(-adviceStep) {
+		s := rise(sNow, upper, bed.Sub(from).Hours(), tauRise)
+		s = decay(s, params.sleepFloor(bed), targetWake.Sub(bed).Hours(), tauDecay)
+		if normalize(s, upper, lower) <= goal {
+			return bed, true
+		}
+	}
+	return from, false
+}
+
+// onsetThreshold is the S level at which the user falls asleep at time t.
+func (b *BioParams) onsetThreshold(t time.Time) float64 {
+	return onsetLevel + thresholdAmplitude*(2*b.circadian(t)-1)
+}
+
+// wakeThreshold is the S level at which the user wakes up at time t.
+func (b *BioParams) wakeThreshold(t time.Time) float64 {
+	return wakeLevel + thresholdAmplitude*(2*b.circadian(t)-1)
+}
+
+// normalize maps a raw S value onto 0 (lower asymptote) .. 1 (upper asymptote).
+func normalize(s, upper, lower float64) float64 {
+	return (s - lower) / (upper - lower)
+}
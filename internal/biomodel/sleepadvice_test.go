@@ -0,0 +1,72 @@
+package biomodel
+
+import (
+	"testing"
+	"time"
+)
+
+// shortNights is a week of nights of the given length, going to bed at 02:00 and ending the
+// morning of 'day'.
+func shortNights(day time.Time, length time.Duration) SleepLog {
+	var log SleepLog
+	for k := 7; k >= 1; k-- {
+		bed := day.Add(-time.Duration(k)*24*time.Hour + 26*time.Hour)
+		log = append(log, SleepEpisode{Start: bed, End: bed.Add(length)})
+	}
+	return log
+}
+
+func TestPredictSleepWindowMatchesPressureWithDebt(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	params := BioParams{Sleep: shortNights(day, 5*time.Hour)}
+	if debt := params.SleepDebt(day.Add(12 * time.Hour)); debt < 5 {
+		t.Fatalf("test setup: want substantial debt, got %.1fh", debt)
+	}
+
+	window, err := PredictSleepWindow(params, day.Add(12*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Log the predicted night and read S the way the forecast does: the advice's wake time should
+	// be where pressure actually reaches the wake threshold.
+	logged := params
+	logged.Sleep = append(append(SleepLog{}, params.Sleep...), SleepEpisode{Start: window.Bedtime, End: window.WakeTime})
+	upper, lower := logged.asymptotes()
+	s, _ := logged.sleepPressure(window.WakeTime.Add(-time.Second))
+	if got, want := normalize(s, upper, lower), logged.wakeThreshold(window.WakeTime); got > want+0.02 {
+		t.Errorf("at the predicted wake %s, S is %.3f but the wake threshold is %.3f", window.WakeTime.Format("15:04"), got, want)
+	}
+
+	rested := BioParams{WakeTime: window.Bedtime.Add(-16 * time.Hour)}
+	restedWindow, err := PredictSleepWindow(rested, window.Bedtime.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if window.Duration() <= restedWindow.Duration() {
+		t.Errorf("with debt the night should be longer: %v, rested %v", window.Duration(), restedWindow.Duration())
+	}
+}
+
+func TestOptimalBedtimeMatchesPressureWithDebt(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	// Mild debt: heavy debt raises the floor past the wake threshold, and no bedtime is enough
+	params := BioParams{Sleep: shortNights(day, 7*time.Hour)}
+	if debt := params.SleepDebt(day.Add(18 * time.Hour)); debt <= 0 {
+		t.Fatalf("test setup: want some debt, got %.1fh", debt)
+	}
+	from := day.Add(18 * time.Hour)
+	wake := day.Add(31 * time.Hour) // 07:00 next morning
+
+	bed, ok := OptimalBedtime(params, from, wake)
+	if !ok {
+		t.Fatalf("no bedtime found between %s and %s", from, wake)
+	}
+	logged := params
+	logged.Sleep = append(append(SleepLog{}, params.Sleep...), SleepEpisode{Start: bed, End: wake})
+	upper, lower := logged.asymptotes()
+	s, _ := logged.sleepPressure(wake.Add(-time.Second))
+	if got, want := normalize(s, upper, lower), logged.wakeThreshold(wake); got > want+0.002 {
+		t.Errorf("going to bed at %s leaves S at %.3f by 07:00, above the wake threshold %.3f", bed.Format("15:04"), got, want)
+	}
+}
@@ -0,0 +1,143 @@
+package biomodel
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// SleepLog is a chronological record of past sleep, optionally followed by planned future nights.
+type SleepLog []SleepEpisode
+
+// SleepRoutine is a habitual night expressed as wall-clock times, e.g. "23:00" -> "07:00".
+// It is used to fill in future nights the user has not slept yet.
+type SleepRoutine struct {
+	Bedtime  string `json:"bedtime"`
+	WakeTime string `json:"wake_time"`
+}
+
+// DefaultRoutine is the 23:00 -> 07:00 night TardiGo assumes when a user has not told us otherwise.
+var DefaultRoutine = SleepRoutine{Bedtime: "23:00", WakeTime: "07:00"}
+
+// Nights plans one episode per night, starting with the night that ends on from's calendar day
+// and continuing for the given number of days. Times are placed in from's location.
+func (r SleepRoutine) Nights(from time.Time, days int) (SleepLog, error) {
+	bedH, bedM, err := parseClock(r.Bedtime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bedtime: %w", err)
+	}
+	wakeH, wakeM, err := parseClock(r.WakeTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wake time: %w", err)
+	}
+
+	// A bedtime later in the clock than the wake time means we go to bed the evening before.
+	crossesMidnight := bedH*60+bedM >= wakeH*60+wakeM
+
+	var log SleepLog
+	for d := 0; d <= days; d++ {
+		day := time.Date(from.Year(), from.Month(), from.Day()+d, 0, 0, 0, 0, from.Location())
+		wake := time.Date(day.Year(), day.Month(), day.Day(), wakeH, wakeM, 0, 0, day.Location())
+		bed := time.Date(day.Year(), day.Month(), day.Day(), bedH, bedM, 0, 0, day.Location())
+		if crossesMidnight {
+			bed = bed.AddDate(0, 0, -1)
+		}
+		log = append(log, SleepEpisode{Start: bed, End: wake, Planned: true})
+	}
+	return log, nil
+}
+
+// Validate checks that every episode ends after it starts and that no two episodes overlap.
+func (l SleepLog) Validate() error {
+	sorted := l.Sorted()
+	for i, ep := range sorted {
+		if !ep.End.After(ep.Start) {
+			return fmt.Errorf("sleep episode starting %s does not end after it starts", ep.Start.Format(time.RFC3339))
+		}
+		if i > 0 && ep.Start.Before(sorted[i-1].End) {
+			return fmt.Errorf("sleep episode starting %s overlaps the previous one", ep.Start.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// Sorted returns a copy of the log in chronological order.
+func (l SleepLog) Sorted() SleepLog {
+	sorted := make(SleepLog, len(l))
+	copy(sorted, l)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+	return sorted
+}
+
+// Extend fills the span from..until with planned nights from the routine, skipping any night
+// that would start before the last logged night ended or that clashes with a logged nap.
+// Logged episodes are kept as-is.
+func (l SleepLog) Extend(r SleepRoutine, from, until time.Time) (SleepLog, error) {
+	sorted := l.Sorted()
+
+	days := int(until.Sub(from).Hours()/24) + 1
+	planned, err := r.Nights(from, days)
+	if err != nil {
+		return nil, err
+	}
+
+	// A nap planned for tomorrow afternoon must not swallow tonight
+	var lastEnd time.Time
+	var naps SleepLog
+	for _, ep := range sorted {
+		if ep.Nap {
+			naps = append(naps, ep)
+		} else if ep.End.After(lastEnd) {
+			lastEnd = ep.End
+		}
+	}
+
+	for _, night := range planned {
+		// Only add nights that start after everything we already know about
+		if night.Start.Before(lastEnd) || naps.overlaps(night) {
+			continue
+		}
+		sorted = append(sorted, night)
+	}
+	return sorted.Sorted(), nil
+}
+
+// overlaps reports whether any episode in the log overlaps ep.
+func (l SleepLog) overlaps(ep SleepEpisode) bool {
+	for _, other := range l {
+		if ep.Start.Before(other.End) && other.Start.Before(ep.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// LastWake returns the end of the most recent episode that finished at or before t.
+func (l SleepLog) LastWake(t time.Time) (time.Time, bool) {
+	last, ok := l.lastEpisode(t)
+	return last.End, ok
+}
+
+// lastEpisode returns the most recent episode that finished at or before t.
+func (l SleepLog) lastEpisode(t time.Time) (SleepEpisode, bool) {
+	var last SleepEpisode
+	found := false
+	for _, ep := range l {
+		if !ep.End.After(t) && ep.End.After(last.End) {
+			last = ep
+			found = true
+		}
+	}
+	return last, found
+}
+
+// parseClock parses a "15:04" wall-clock string into hours and minutes.
+func parseClock(clock string) (int, int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, 0, err
+	}
+	return t.Hour(), t.Minute(), nil
+}
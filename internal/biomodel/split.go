@@ -0,0 +1,195 @@
+package biomodel
+
+import (
+	"fmt"
+	"time"
+)
+
+// Focus blocks. A splittable task is booked as several chunks, each at least its minimum and at
+// most its maximum length, so a four-hour task no longer needs four free hours in a row.
+const (
+	DefaultMinChunkMinutes = 30 // Shorter than this and the ramp-up eats the block
+	DefaultMaxChunkMinutes = 90 // About one ultradian cycle of sustained focus
+)
+
+// Breaks inserted by BreakPolicy.
+const (
+	BreakTaskName = "Break"
+	BreakFitScore = "Recovery"
+)
+
+// BreakPolicy makes every block of at least Effort (a task, or a chunk of one) be followed by
+// Minutes of rest. Rest has to be free time: a hard block is not booked where the break would
+// collide with something else, unless sleep follows (which is recovery enough).
+type BreakPolicy struct {
+	Effort  int `json:"effort"`  // Default 7, the effort the sleep-debt budget also rations
+	Minutes int `json:"minutes"` // Break length, 5-120
+}
+
+// Validate checks the break length and effort threshold. A nil policy is valid.
+func (p *BreakPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.Minutes < 5 || p.Minutes > 120 {
+		return fmt.Errorf("breaks: minutes must be 5-120, got %d", p.Minutes)
+	}
+	if p.Effort < 0 || p.Effort > 10 {
+		return fmt.Errorf("breaks: effort must be 1-10 (or 0 for the default), got %d", p.Effort)
+	}
+	return nil
+}
+
+// after reports whether a block of the task's effort must be followed by a break.
+func (p *BreakPolicy) after(task Task) bool {
+	if p == nil {
+		return false
+	}
+	effort := p.Effort
+	if effort == 0 {
+		effort = highEffortLevel
+	}
+	return task.Effort >= effort
+}
+
+// validateSplit checks the chunk limits: only on splittable tasks, positive, in order, and able to
+// cut the task into chunks (100 minutes will not go into blocks of 60 to 90).
+func (t Task) validateSplit() error {
+	if !t.Splittable {
+		if t.MinChunk != 0 || t.MaxChunk != 0 {
+			return fmt.Errorf("task %q: min/max_chunk_minutes need splittable", t.Name)
+		}
+		return nil
+	}
+	if t.MinChunk < 0 || t.MaxChunk < 0 {
+		return fmt.Errorf("task %q: chunk lengths must be positive", t.Name)
+	}
+	min, max := t.chunkBounds()
+	if min > max {
+		return fmt.Errorf("task %q: min_chunk_minutes %d is longer than max_chunk_minutes %d", t.Name, min, max)
+	}
+	if t.Duration > max && !tileable(t.Duration, min, max) {
+		return fmt.Errorf("task %q: %d minutes cannot be split into blocks of %d to %d minutes", t.Name, t.Duration, min, max)
+	}
+	return nil
+}
+
+// tileable reports whether the minutes can be cut into chunks of min to max minutes each.
+// The fewest chunks that are short enough are also the ones most likely to be long enough.
+func tileable(minutes, min, max int) bool {
+	chunks := (minutes + max - 1) / max
+	return chunks*min <= minutes
+}
+
+// chunkBounds returns the task's chunk limits in minutes, with the defaults filled in.
+func (t Task) chunkBounds() (int, int) {
+	min, max := t.MinChunk, t.MaxChunk
+	if min == 0 {
+		min = DefaultMinChunkMinutes
+		if max != 0 && max < min {
+			min = max
+		}
+	}
+	if max == 0 {
+		max = DefaultMaxChunkMinutes
+		if max < min {
+			max = min
+		}
+	}
+	return min, max
+}
+
+// chunkLengths lists the lengths (in minutes) the next chunk may take when 'remaining' minutes of
+// the task are left, longest first: the planner prefers few long blocks to many short ones.
+// A length is only offered if what it leaves over can still be cut into chunks; validateSplit
+// makes sure the whole task can, so there is always at least one.
+// Unsplittable tasks have one chunk, the whole task.
+func (t Task) chunkLengths(remaining int, opts ScheduleOptions) []int {
+	if !t.Splittable {
+		return []int{remaining}
+	}
+	min, max := t.chunkBounds()
+	if remaining <= max {
+		return []int{remaining}
+	}
+
+	var lengths []int
+	step := int(opts.slotLength().Minutes())
+	for length := max; length >= min; length -= step {
+		if tileable(remaining-length, min, max) {
+			lengths = append(lengths, length)
+		}
+	}
+	if n := len(lengths); (n == 0 || lengths[n-1] != min) && tileable(remaining-min, min, max) {
+		lengths = append(lengths, min)
+	}
+	return lengths
+}
+
+// chunk is the task cut down to a block of the given minutes, with the same effort and constraints.
+func (t Task) chunk(minutes int) Task {
+	t.Duration = minutes
+	return t
+}
+
+// adjoins reports whether a block from start to end touches one of the blocks already booked for
+// the same task. Two chunks back to back would just be one chunk longer than the maximum.
+func adjoins(parts []TimeBlock, start, end time.Time) bool {
+	for _, p := range parts {
+		if start.Equal(p.End) || end.Equal(p.Start) {
+			return true
+		}
+	}
+	return false
+}
+
+// breakSlots returns the slots from index 'from' on that the break after a block of the task
+// needs, and false if one of them is taken. The break stops early at sleep or the end of the horizon.
+func breakSlots(task Task, slots []Slot, from int, opts ScheduleOptions) ([]int, bool) {
+	if !opts.Breaks.after(task) {
+		return nil, true
+	}
+	var rest []int
+	needed := opts.slotsFor(time.Duration(opts.Breaks.Minutes) * time.Minute)
+	for k := from; k < from+needed && k < len(slots); k++ {
+		if slots[k].Asleep {
+			break
+		}
+		if slots[k].IsBooked {
+			return nil, false
+		}
+		rest = append(rest, k)
+	}
+	return rest, true
+}
+
+// breakItem books the break slots and returns the "Break" item for them (nil if there are none).
+func breakItem(rest []int, slots []Slot, opts ScheduleOptions) *placement {
+	if len(rest) == 0 {
+		return nil
+	}
+	var booked []Slot
+	for _, k := range rest {
+		slots[k].IsBooked = true
+		booked = append(booked, slots[k])
+	}
+	minutes := opts.Breaks.Minutes
+	if available := len(rest) * int(opts.slotLength().Minutes()); available < minutes {
+		minutes = available // Cut short by the end of the horizon
+	}
+	weights := make([]float64, len(booked))
+	for i := range weights {
+		weights[i] = 1 / float64(len(booked))
+	}
+	return &placement{
+		ScheduleItem: ScheduleItem{
+			StartTime:    booked[0].Time.Format(time.RFC3339),
+			TaskName:     BreakTaskName,
+			Duration:     minutes,
+			PredictedCap: averageCapacity(booked, weights),
+			FitScore:     BreakFitScore,
+		},
+		slots:   rest,
+		weights: weights,
+	}
+}
@@ -0,0 +1,97 @@
+package biomodel
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChunkLengths(t *testing.T) {
+	tests := []struct {
+		name      string
+		task      Task
+		remaining int
+		want      []int
+	}{
+		{"unsplittable", Task{Duration: 240}, 240, []int{240}},
+		{"fits in one chunk", Task{Splittable: true}, 80, []int{80}},
+		{"defaults, longest first", Task{Splittable: true}, 240, []int{90, 60, 30}},
+		{"leftover must make a chunk", Task{Splittable: true}, 100, []int{60, 30}},
+		{"leftover must tile", Task{Splittable: true, MinChunk: 60, MaxChunk: 90}, 200, []int{60}}, // 90 would leave 110
+		{"either leftover tiles", Task{Splittable: true, MinChunk: 60, MaxChunk: 90}, 150, []int{90, 60}},
+		{"uneven bounds", Task{Splittable: true, MinChunk: 45, MaxChunk: 60}, 105, []int{60, 45}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.task.chunkLengths(tt.remaining, ScheduleOptions{}); !slices.Equal(got, tt.want) {
+				t.Errorf("chunkLengths(%d) = %v, want %v", tt.remaining, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSplitRejectsUntileableDurations(t *testing.T) {
+	tests := []struct {
+		task Task
+		ok   bool
+	}{
+		{Task{Name: "a", Duration: 100, Splittable: true, MinChunk: 60, MaxChunk: 90}, false},
+		{Task{Name: "b", Duration: 110, Splittable: true, MinChunk: 60, MaxChunk: 90}, false},
+		{Task{Name: "c", Duration: 120, Splittable: true, MinChunk: 60, MaxChunk: 90}, true},
+		{Task{Name: "d", Duration: 40, Splittable: true, MinChunk: 60, MaxChunk: 90}, true}, // One short block
+		{Task{Name: "e", Duration: 95, Splittable: true}, true},
+	}
+	for _, tt := range tests {
+		err := tt.task.validateSplit()
+		if (err == nil) != tt.ok {
+			t.Errorf("task %q (%d minutes): got error %v, want ok %v", tt.task.Name, tt.task.Duration, err, tt.ok)
+		}
+		if err != nil && !strings.Contains(err.Error(), "cannot be split") {
+			t.Errorf("task %q: error %q does not say why", tt.task.Name, err)
+		}
+	}
+}
+
+func TestAllocateSplitsIntoSeparateWindows(t *testing.T) {
+	start := time.Date(2026, 3, 10, 8, 0, 0, 0, time.UTC)
+	slots := flatSlots(start, 0.9, 0.9, 0.9, 0.3, 0.3, 0.3, 0.9, 0.9, 0.9, 0.3, 0.3, 0.3)
+	tasks := []Task{{Name: "write", Duration: 180, Effort: 5, Splittable: true}}
+
+	schedule, _ := allocate(tasks, slots, -1, ScheduleOptions{})
+	if len(schedule) != 2 {
+		t.Fatalf("want two blocks, got %+v", schedule)
+	}
+	for i, want := range []string{"2026-03-10T08:00:00Z", "2026-03-10T11:00:00Z"} {
+		item := schedule[i]
+		if item.StartTime != want || item.Duration != 90 || item.Part != i+1 || item.Parts != 2 {
+			t.Errorf("block %d: got %s, %d minutes, part %d of %d; want %s, 90 minutes, part %d of 2",
+				i, item.StartTime, item.Duration, item.Part, item.Parts, want, i+1)
+		}
+	}
+}
+
+func TestAllocateBooksBreakAfterHardBlock(t *testing.T) {
+	start := time.Date(2026, 3, 10, 8, 0, 0, 0, time.UTC)
+	slots := flatSlots(start, 0.9, 0.9, 0.9, 0.9, 0.9, 0.9)
+	slots[2].IsBooked = true // 09:00 is taken, so a block ending then has nowhere to rest
+
+	opts := ScheduleOptions{Breaks: &BreakPolicy{Minutes: 30}}
+	schedule, _ := allocate([]Task{{Name: "proof", Duration: 60, Effort: 8}}, slots, -1, opts)
+	if len(schedule) != 2 {
+		t.Fatalf("want the task and its break, got %+v", schedule)
+	}
+	task, rest := schedule[0], schedule[1]
+	if task.StartTime != "2026-03-10T09:30:00Z" {
+		t.Errorf("task starts at %s, want 09:30 (08:00 would run into the booked slot for its break)", task.StartTime)
+	}
+	if rest.TaskName != BreakTaskName || rest.FitScore != BreakFitScore || rest.StartTime != "2026-03-10T10:30:00Z" || rest.Duration != 30 {
+		t.Errorf("got break %+v, want a 30-minute %q at 10:30", rest, BreakTaskName)
+	}
+
+	// Easy work needs no break
+	schedule, _ = allocate([]Task{{Name: "email", Duration: 60, Effort: 3}}, flatSlots(start, 0.9, 0.9, 0.9), -1, opts)
+	if len(schedule) != 1 {
+		t.Errorf("effort 3 is below the policy's default of %d, but got %+v", highEffortLevel, schedule)
+	}
+}
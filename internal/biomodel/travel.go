@@ -0,0 +1,115 @@
+package biomodel
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Re-entrainment speed after crossing time zones. The body clock advances (eastward travel)
+// more slowly than it delays (westward travel).
+const (
+	advanceHoursPerDay = 1.0
+	delayHoursPerDay   = 1.5
+)
+
+// TripLeg is a single journey that moves the user into another time zone.
+type TripLeg struct {
+	Arrival time.Time `json:"arrival"`
+	From    string    `json:"from"` // IANA zone the user leaves, e.g. "Europe/Berlin"
+	To      string    `json:"to"`   // IANA zone the user arrives in, e.g. "America/Los_Angeles"
+}
+
+// Itinerary is the user's travel plan, in any order.
+type Itinerary []TripLeg
+
+// Validate checks that every leg names both zones and that they resolve.
+func (it Itinerary) Validate() error {
+	for _, leg := range it {
+		if leg.From == "" || leg.To == "" {
+			return fmt.Errorf("trip arriving %s: both 'from' and 'to' zones are required", leg.Arrival.Format(time.RFC3339))
+		}
+		if _, err := loadZone(leg.From); err != nil {
+			return fmt.Errorf("trip arriving %s: %w", leg.Arrival.Format(time.RFC3339), err)
+		}
+		if _, err := loadZone(leg.To); err != nil {
+			return fmt.Errorf("trip arriving %s: %w", leg.Arrival.Format(time.RFC3339), err)
+		}
+	}
+	return nil
+}
+
+// bodyOffset returns the UTC offset (in hours) the circadian pacemaker is entrained to at t.
+// Before the first trip the body runs on the origin zone; after each arrival it drifts toward
+// the new zone at the direction-dependent rate, taking the shorter way around the clock.
+func (it Itinerary) bodyOffset(t time.Time) float64 {
+	legs := make(Itinerary, len(it))
+	copy(legs, it)
+	sort.Slice(legs, func(i, j int) bool {
+		return legs[i].Arrival.Before(legs[j].Arrival)
+	})
+
+	body := zoneOffset(legs[0].From, legs[0].Arrival)
+	targetZone := legs[0].From
+	cursor := legs[0].Arrival
+
+	for _, leg := range legs {
+		if leg.Arrival.After(t) {
+			break
+		}
+		body = reentrain(body, zoneOffset(targetZone, leg.Arrival), leg.Arrival.Sub(cursor))
+		targetZone = leg.To
+		cursor = leg.Arrival
+	}
+	if t.After(cursor) {
+		// Evaluated at t so a DST switch during the stay is followed like any other shift
+		body = reentrain(body, zoneOffset(targetZone, t), t.Sub(cursor))
+	}
+	return body
+}
+
+// reentrain moves the body offset toward the target offset over the elapsed time.
+func reentrain(body, target float64, elapsed time.Duration) float64 {
+	// Shortest way around the 24h clock: +9h east is better treated as +9 than -15
+	diff := math.Remainder(target-body, 24)
+	if diff == 0 || elapsed <= 0 {
+		return body
+	}
+
+	days := elapsed.Hours() / 24
+	rate := advanceHoursPerDay // Local clock ahead of the body: phase advance
+	if diff < 0 {
+		rate = delayHoursPerDay
+	}
+	shift := math.Min(math.Abs(diff), rate*days)
+	return body + math.Copysign(shift, diff)
+}
+
+// zoneOffset returns a zone's UTC offset in hours at t. Unknown zones count as UTC;
+// Itinerary.Validate reports them before we get here.
+func zoneOffset(name string, t time.Time) float64 {
+	loc, err := loadZone(name)
+	if err != nil {
+		return 0
+	}
+	_, seconds := t.In(loc).Zone()
+	return float64(seconds) / 3600.0
+}
+
+// zoneCache avoids re-reading tzdata for every model evaluation.
+var zoneCache sync.Map
+
+// loadZone is time.LoadLocation with a cache.
+func loadZone(name string) (*time.Location, error) {
+	if loc, ok := zoneCache.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q: %w", name, err)
+	}
+	zoneCache.Store(name, loc)
+	return loc, nil
+}
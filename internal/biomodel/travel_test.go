@@ -0,0 +1,70 @@
+package biomodel
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestReentrain(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		name         string
+		body, target float64
+		elapsed      time.Duration
+		want         float64
+	}{
+		{"eastward advances 1h a day", 0, 6, 2 * day, 2},
+		{"westward delays 1.5h a day", 0, -6, 2 * day, -3},
+		{"stops at the target", 0, 3, 10 * day, 3},
+		{"part of a day", 0, -6, 12 * time.Hour, -0.75},
+		{"nothing elapsed", 0, 6, 0, 0},
+		{"same clock, a day apart", 5, -19, day, 5},
+		{"east across +12h", 10, -10, day, 11},    // 4h ahead the short way, not 20h behind
+		{"west across -12h", -10, 10, day, -11.5}, // 4h behind the short way
+		{"nine ahead is not fifteen behind", 0, 9, day, 1},
+		{"exactly half a day counts as ahead", 0, 12, day, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reentrain(tt.body, tt.target, tt.elapsed); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("reentrain(%v, %v, %v) = %v, want %v", tt.body, tt.target, tt.elapsed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestItineraryBodyOffset(t *testing.T) {
+	// Zones without daylight saving, so the offsets are fixed
+	out := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	back := out.Add(3 * 24 * time.Hour)
+	roundTrip := Itinerary{
+		{Arrival: back, From: "Asia/Tokyo", To: "UTC"}, // Out of order on purpose
+		{Arrival: out, From: "UTC", To: "Asia/Tokyo"},
+	}
+	pacific := Itinerary{{Arrival: out, From: "Asia/Tokyo", To: "Pacific/Honolulu"}}
+
+	tests := []struct {
+		name string
+		it   Itinerary
+		at   time.Time
+		want float64
+	}{
+		{"before leaving", roundTrip, out.Add(-time.Hour), 0},
+		{"two days in Tokyo", roundTrip, out.Add(48 * time.Hour), 2},
+		{"landing home", roundTrip, back, 3},
+		{"a day home", roundTrip, back.Add(24 * time.Hour), 1.5},
+		{"two days home", roundTrip, back.Add(48 * time.Hour), 0},
+		{"a week later", roundTrip, back.Add(7 * 24 * time.Hour), 0},
+		// Honolulu (-10) is 19h behind Tokyo (+9), or 5h ahead across the date line
+		{"across the date line", pacific, out.Add(48 * time.Hour), 11},
+		{"entrained across the date line", pacific, out.Add(10 * 24 * time.Hour), 14}, // -10 around the clock
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.it.bodyOffset(tt.at); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("body offset at %s = %v, want %v", tt.at.Format("Mon 15:04"), got, tt.want)
+			}
+		})
+	}
+}
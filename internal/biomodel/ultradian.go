@@ -0,0 +1,53 @@
+package biomodel
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Kleitman's Basic Rest-Activity Cycle: focus waxes and wanes roughly every 90 minutes during the day.
+const (
+	DefaultUltradianPeriod = 90.0 // Minutes
+	minUltradianPeriod     = 60.0
+	maxUltradianPeriod     = 150.0
+	maxUltradianAmplitude  = 0.2 // Capacity swing either side of the baseline
+)
+
+// Ultradian describes a user's BRAC. The zero value switches it off.
+type Ultradian struct {
+	Period    float64 `json:"period_minutes"` // Cycle length. Zero falls back to DefaultUltradianPeriod.
+	Amplitude float64 `json:"amplitude"`      // Peak capacity added (and trough removed). Typical 0.05 - 0.1.
+}
+
+// Validate keeps the rhythm within physiologically plausible bounds.
+func (u Ultradian) Validate() error {
+	if u.Amplitude < 0 || u.Amplitude > maxUltradianAmplitude {
+		return fmt.Errorf("ultradian amplitude must be between 0 and %.1f, got %.2f", maxUltradianAmplitude, u.Amplitude)
+	}
+	if u.Period != 0 && (u.Period < minUltradianPeriod || u.Period > maxUltradianPeriod) {
+		return fmt.Errorf("ultradian period must be between %.0f and %.0f minutes, got %.0f", minUltradianPeriod, maxUltradianPeriod, u.Period)
+	}
+	return nil
+}
+
+// ultradian returns the BRAC's contribution to capacity at targetTime (between -Amplitude and
+// +Amplitude). The cycle restarts at every wake-up, starting on the rising edge, and is silent
+// while asleep or without a known wake-up.
+//
+//	U(t) = A * sin(2π * (t - wake) / Period)
+func (b *BioParams) ultradian(targetTime time.Time) float64 {
+	if b.Ultradian.Amplitude <= 0 {
+		return 0
+	}
+	last, ok := b.lastEpisode(targetTime)
+	if !ok {
+		return 0
+	}
+	period := b.Ultradian.Period
+	if period <= 0 {
+		period = DefaultUltradianPeriod
+	}
+	minutes := targetTime.Sub(last.End).Minutes()
+	return b.Ultradian.Amplitude * math.Sin(2*math.Pi*minutes/period)
+}
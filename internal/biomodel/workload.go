@@ -0,0 +1,70 @@
+package biomodel
+
+import (
+	"math"
+	"time"
+)
+
+// Cognitive depletion from booked work. Effortful tasks draw down capacity for the slots that follow;
+// the deficit fades exponentially during breaks (or easier work).
+const (
+	depletionPerHour = 0.12 // Capacity lost after an hour of effort-10 work
+	depletionDecay   = 1.0  // Hours. Time constant of recovering from it.
+	maxDepletion     = 0.5  // Even a brutal day leaves some capacity for the next slot
+)
+
+// booking is a block of work already placed on the schedule.
+type booking struct {
+	Start, End time.Time
+	Load       float64 // Effort-weighted hours: effort/10 * duration
+}
+
+// workload is every booking made so far.
+type workload []booking
+
+// newBooking turns a task placed at start into its load.
+func newBooking(task Task, start time.Time) booking {
+	return booking{
+		Start: start,
+		End:   start.Add(time.Duration(task.Duration) * time.Minute),
+		Load:  float64(task.Effort) / 10.0 * float64(task.Duration) / 60.0,
+	}
+}
+
+// depletion returns the capacity lost at t to work that finished before it.
+//
+//	D(t) = Σ depletionPerHour * Load * e^(-(t - End) / depletionDecay)
+func (w workload) depletion(t time.Time) float64 {
+	total := 0.0
+	for _, b := range w {
+		if t.Before(b.End) {
+			continue
+		}
+		total += depletionPerHour * b.Load * math.Exp(-t.Sub(b.End).Hours()/depletionDecay)
+	}
+	return math.Min(maxDepletion, total)
+}
+
+// deplete re-evaluates the depletion of every free slot. Booked slots keep the depletion they
+// were planned with until finalize reads the finished plan.
+func (w workload) deplete(slots []Slot) {
+	for k := range slots {
+		if !slots[k].IsBooked {
+			slots[k].Depletion = w.depletion(slots[k].Time)
+		}
+	}
+}
+
+// drain returns the capacity a candidate booking would take from work already booked after it.
+// The allocator subtracts it when ranking the candidate, so a new task is not squeezed in right
+// before a hard block any more than right after one.
+func (w workload) drain(candidate booking) float64 {
+	total := 0.0
+	for _, b := range w {
+		if b.Start.Before(candidate.End) {
+			continue
+		}
+		total += depletionPerHour * candidate.Load * math.Exp(-b.Start.Sub(candidate.End).Hours()/depletionDecay)
+	}
+	return math.Min(maxDepletion, total)
+}
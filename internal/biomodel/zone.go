@@ -0,0 +1,40 @@
+package biomodel
+
+import (
+	"fmt"
+	"time"
+)
+
+// ValidateTimeZone checks that name is an IANA zone, e.g. "Europe/Berlin". Empty is valid and
+// means "the location carried by the times passed in".
+func ValidateTimeZone(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, err := loadZone(name); err != nil {
+		return fmt.Errorf("invalid time_zone: %w", err)
+	}
+	return nil
+}
+
+// Location returns the user's time zone. Without one (or with an unknown one) it is nil, and
+// local falls back to the location of whatever time it is given.
+func (b BioParams) Location() *time.Location {
+	if b.TimeZone == "" {
+		return nil
+	}
+	loc, err := loadZone(b.TimeZone)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+// local expresses t in the user's time zone. All clock-based maths (circadian phase, the
+// habitual night) go through here so results do not depend on the server's or caller's zone.
+func (b BioParams) local(t time.Time) time.Time {
+	if loc := b.Location(); loc != nil {
+		return t.In(loc)
+	}
+	return t
+}
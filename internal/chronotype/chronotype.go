@@ -0,0 +1,27 @@
+// Package chronotype scores chronotype questionnaires and maps them onto bio-model parameters.
+package chronotype
+
+import "github.com/sitanshunandan/tardigo/internal/biomodel"
+
+// Bounds for calibrated parameters, so a single odd answer cannot produce a nonsensical model.
+const (
+	maxLag             = 4.0 // Hours either side of the population average
+	minFatigueRate     = 12.0
+	maxFatigueRate     = 20.0
+	defaultFatigueRate = biomodel.DefaultFatigueRate
+)
+
+// Result is a scored questionnaire and the parameters it implies.
+type Result struct {
+	Method        string  `json:"method"`   // "MEQ" or "MCTQ"
+	Score         float64 `json:"score"`    // MEQ total, or MSFsc in hours after midnight
+	Category      string  `json:"category"` // e.g. "Moderate Evening"
+	ChronotypeLag float64 `json:"chronotype_lag"`
+	FatigueRate   float64 `json:"fatigue_rate"`
+}
+
+// Apply copies the calibrated values into a user's BioParams.
+func (r Result) Apply(params *biomodel.BioParams) {
+	params.ChronotypeLag = r.ChronotypeLag
+	params.FatigueRate = r.FatigueRate
+}
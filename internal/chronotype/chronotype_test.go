@@ -0,0 +1,169 @@
+package chronotype
+
+import (
+	"math"
+	"testing"
+)
+
+// answers picks one option on every MEQ item.
+func answers(pick func(item Item) int) []int {
+	choices := make([]int, len(MEQItems))
+	for i, item := range MEQItems {
+		choices[i] = pick(item)
+	}
+	return choices
+}
+
+// extreme picks the option scoring most (or least) on an item; items run both ways.
+func extreme(most bool) func(Item) int {
+	return func(item Item) int {
+		best := 0
+		for i, opt := range item.Options {
+			if most && opt.Score > item.Options[best].Score || !most && opt.Score < item.Options[best].Score {
+				best = i
+			}
+		}
+		return best
+	}
+}
+
+func TestScoreMEQ(t *testing.T) {
+	tests := []struct {
+		name    string
+		answers []int
+		want    int
+		ok      bool
+	}{
+		{"most morning", answers(extreme(true)), meqMax, true},
+		{"most evening", answers(extreme(false)), meqMin, true},
+		{"too few", answers(extreme(true))[1:], 0, false},
+		{"option out of range", answers(func(item Item) int { return len(item.Options) }), 0, false},
+		{"negative option", answers(func(Item) int { return -1 }), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ScoreMEQ(tt.answers)
+			if (err == nil) != tt.ok {
+				t.Fatalf("got error %v, want ok %v", err, tt.ok)
+			}
+			if got != tt.want {
+				t.Errorf("got score %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromMEQ(t *testing.T) {
+	tests := []struct {
+		score    int
+		category string
+		lag      float64
+	}{
+		{meqMin, "Definite Evening", 3.4},
+		{30, "Definite Evening", 2.0},
+		{31, "Moderate Evening", 1.9},
+		{41, "Moderate Evening", 0.9},
+		{42, "Neither", 0.8},
+		{meqNeutral, "Neither", 0},
+		{58, "Neither", -0.8},
+		{59, "Moderate Morning", -0.9},
+		{69, "Moderate Morning", -1.9},
+		{70, "Definite Morning", -2.0},
+		{meqMax, "Definite Morning", -3.6},
+	}
+	for _, tt := range tests {
+		got, err := FromMEQ(tt.score)
+		if err != nil {
+			t.Errorf("score %d: %v", tt.score, err)
+			continue
+		}
+		if got.Category != tt.category || math.Abs(got.ChronotypeLag-tt.lag) > 1e-9 {
+			t.Errorf("score %d: got %q with lag %.2f, want %q with lag %.2f", tt.score, got.Category, got.ChronotypeLag, tt.category, tt.lag)
+		}
+		if got.FatigueRate < minFatigueRate || got.FatigueRate > maxFatigueRate {
+			t.Errorf("score %d: fatigue rate %.2f outside %v-%v", tt.score, got.FatigueRate, minFatigueRate, maxFatigueRate)
+		}
+	}
+
+	for _, score := range []int{meqMin - 1, meqMax + 1} {
+		if _, err := FromMEQ(score); err == nil {
+			t.Errorf("score %d is outside the questionnaire's range but was accepted", score)
+		}
+	}
+}
+
+func TestFromMCTQ(t *testing.T) {
+	tests := []struct {
+		name     string
+		answers  MCTQAnswers
+		msfsc    float64
+		category string
+		lag      float64
+	}{
+		{
+			// Same night every day: no oversleep to correct, MSF = 23:00 + 4h
+			"regular sleeper",
+			MCTQAnswers{WorkDaysPerWeek: 5, WorkSleepOnset: "23:00", WorkSleepEnd: "07:00", FreeSleepOnset: "23:00", FreeSleepEnd: "07:00"},
+			3, "Moderate Morning", -1.25,
+		},
+		{
+			// 10h on free days against a weekly average of 60/7h: MSF 06:00 moves back by half the oversleep
+			"oversleeps on free days",
+			MCTQAnswers{WorkDaysPerWeek: 5, WorkSleepOnset: "23:00", WorkSleepEnd: "07:00", FreeSleepOnset: "01:00", FreeSleepEnd: "11:00"},
+			6 - (10-60.0/7)/2, "Moderate Evening", 6 - (10-60.0/7)/2 - mctqReferenceMSF,
+		},
+		{
+			// Shorter free nights are not corrected
+			"sleeps less on free days",
+			MCTQAnswers{WorkDaysPerWeek: 5, WorkSleepOnset: "22:00", WorkSleepEnd: "08:00", FreeSleepOnset: "00:00", FreeSleepEnd: "06:00"},
+			3, "Moderate Morning", -1.25,
+		},
+		{
+			// Mid-sleep before midnight wraps to 22:30, which is an early bird, not 18h late
+			"mid-sleep before midnight",
+			MCTQAnswers{WorkDaysPerWeek: 5, WorkSleepOnset: "19:00", WorkSleepEnd: "02:00", FreeSleepOnset: "19:00", FreeSleepEnd: "02:00"},
+			22.5, "Definite Morning", -maxLag,
+		},
+		{
+			"late owl is clamped",
+			MCTQAnswers{WorkDaysPerWeek: 0, FreeSleepOnset: "05:00", FreeSleepEnd: "13:00", WorkSleepOnset: "05:00", WorkSleepEnd: "13:00"},
+			9, "Definite Evening", maxLag,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromMCTQ(tt.answers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(got.Score-tt.msfsc) > 1e-9 {
+				t.Errorf("got MSFsc %.3f, want %.3f", got.Score, tt.msfsc)
+			}
+			if got.Category != tt.category || math.Abs(got.ChronotypeLag-tt.lag) > 1e-9 {
+				t.Errorf("got %q with lag %.3f, want %q with lag %.3f", got.Category, got.ChronotypeLag, tt.category, tt.lag)
+			}
+		})
+	}
+}
+
+func TestFromMCTQRejectsBadAnswers(t *testing.T) {
+	valid := MCTQAnswers{WorkDaysPerWeek: 5, WorkSleepOnset: "23:00", WorkSleepEnd: "07:00", FreeSleepOnset: "00:00", FreeSleepEnd: "09:00"}
+	tests := []struct {
+		name   string
+		change func(*MCTQAnswers)
+	}{
+		{"too many work days", func(a *MCTQAnswers) { a.WorkDaysPerWeek = 8 }},
+		{"not a clock time", func(a *MCTQAnswers) { a.FreeSleepOnset = "midnight" }},
+		{"wakes before falling asleep", func(a *MCTQAnswers) { a.WorkSleepOnset, a.WorkSleepEnd = "07:00", "06:00" }},
+	}
+	for _, tt := range tests {
+		a := valid
+		tt.change(&a)
+		if _, err := FromMCTQ(a); err == nil {
+			t.Errorf("%s: got no error for %+v", tt.name, a)
+		}
+	}
+	if _, err := FromMCTQ(valid); err != nil {
+		t.Errorf("test setup: valid answers rejected: %v", err)
+	}
+}
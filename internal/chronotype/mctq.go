@@ -0,0 +1,124 @@
+package chronotype
+
+import (
+	"fmt"
+	"time"
+)
+
+// MCTQAnswers are the core Munich ChronoType Questionnaire inputs.
+// Clock times use "15:04"; a sleep onset after midnight is simply e.g. "00:45".
+type MCTQAnswers struct {
+	WorkDaysPerWeek int    `json:"work_days_per_week"`
+	WorkSleepOnset  string `json:"work_sleep_onset"` // When you fall asleep before a work day
+	WorkSleepEnd    string `json:"work_sleep_end"`   // When you wake up on a work day
+	FreeSleepOnset  string `json:"free_sleep_onset"` // When you fall asleep before a free day
+	FreeSleepEnd    string `json:"free_sleep_end"`   // When you wake up on a free day (no alarm)
+}
+
+// Population reference for MSFsc (mid-sleep on free days, sleep-corrected), in hours after midnight.
+// Roenneberg's large European samples centre around 04:15.
+const mctqReferenceMSF = 4.25
+
+// ScoreMCTQ computes MSFsc, the MCTQ's chronotype marker, and the average weekly sleep duration.
+//
+//	MSF   = free-day onset + free-day duration / 2
+//	MSFsc = MSF - (SDf - SDweek) / 2   (only when people oversleep on free days)
+func ScoreMCTQ(a MCTQAnswers) (msfsc float64, sleepWeek float64, err error) {
+	if a.WorkDaysPerWeek < 0 || a.WorkDaysPerWeek > 7 {
+		return 0, 0, fmt.Errorf("work days per week must be 0-7, got %d", a.WorkDaysPerWeek)
+	}
+
+	_, workDur, err := sleepSpan(a.WorkSleepOnset, a.WorkSleepEnd)
+	if err != nil {
+		return 0, 0, fmt.Errorf("work days: %w", err)
+	}
+	freeOnset, freeDur, err := sleepSpan(a.FreeSleepOnset, a.FreeSleepEnd)
+	if err != nil {
+		return 0, 0, fmt.Errorf("free days: %w", err)
+	}
+
+	freeDays := 7 - a.WorkDaysPerWeek
+	sleepWeek = (workDur*float64(a.WorkDaysPerWeek) + freeDur*float64(freeDays)) / 7.0
+
+	msf := freeOnset + freeDur/2.0
+	msfsc = msf
+	if freeDur > workDur {
+		msfsc = msf - (freeDur-sleepWeek)/2.0
+	}
+	return wrapHours(msfsc), sleepWeek, nil
+}
+
+// FromMCTQ maps the questionnaire onto model parameters.
+// The circadian lag is the distance of MSFsc from the population reference, and people who need
+// more sleep than the default 8 hours are treated as building sleep pressure faster.
+func FromMCTQ(a MCTQAnswers) (Result, error) {
+	msfsc, sleepWeek, err := ScoreMCTQ(a)
+	if err != nil {
+		return Result{}, err
+	}
+
+	lag := msfsc - mctqReferenceMSF
+	if lag > 12 {
+		lag -= 24 // e.g. an MSFsc of 23:00 is an early bird, not 19h late
+	}
+
+	return Result{
+		Method:        "MCTQ",
+		Score:         msfsc,
+		Category:      mctqCategory(lag),
+		ChronotypeLag: clamp(lag, -maxLag, maxLag),
+		FatigueRate:   clamp(defaultFatigueRate*8.0/sleepWeek, minFatigueRate, maxFatigueRate),
+	}, nil
+}
+
+// mctqCategory labels the lag in the same vocabulary as the MEQ.
+func mctqCategory(lag float64) string {
+	switch {
+	case lag <= -2:
+		return "Definite Morning"
+	case lag <= -1:
+		return "Moderate Morning"
+	case lag < 1:
+		return "Neither"
+	case lag < 2:
+		return "Moderate Evening"
+	default:
+		return "Definite Evening"
+	}
+}
+
+// sleepSpan parses onset/end clock times and returns the onset (hours after midnight, may be
+// negative for evening onsets) and the duration in hours.
+func sleepSpan(onset, end string) (float64, float64, error) {
+	on, err := time.Parse("15:04", onset)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid sleep onset %q: %w", onset, err)
+	}
+	off, err := time.Parse("15:04", end)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid sleep end %q: %w", end, err)
+	}
+
+	onHours := float64(on.Hour()) + float64(on.Minute())/60.0
+	offHours := float64(off.Hour()) + float64(off.Minute())/60.0
+	if onHours >= 12 {
+		onHours -= 24 // Evening onset belongs to the previous calendar day
+	}
+
+	duration := offHours - onHours
+	if duration <= 0 || duration > 16 {
+		return 0, 0, fmt.Errorf("sleep from %s to %s is not a plausible night", onset, end)
+	}
+	return onHours, duration, nil
+}
+
+// wrapHours keeps a clock time in [0, 24).
+func wrapHours(h float64) float64 {
+	for h < 0 {
+		h += 24
+	}
+	for h >= 24 {
+		h -= 24
+	}
+	return h
+}
@@ -0,0 +1,124 @@
+package chronotype
+
+import (
+	"fmt"
+	"math"
+)
+
+// Option is one answer to a questionnaire item and the points it scores.
+type Option struct {
+	Label string `json:"label"`
+	Score int    `json:"score"`
+}
+
+// Item is a single multiple-choice question.
+type Item struct {
+	Question string   `json:"question"`
+	Options  []Option `json:"options"`
+}
+
+// MEQItems is the full 19-item Horne & Östberg (1976) Morningness-Eveningness Questionnaire.
+// Totals range from 16 (definite evening) to 86 (definite morning).
+var MEQItems = []Item{
+	{"If you were entirely free to plan your day, what time would you get up?", []Option{
+		{"05:00-06:30", 5}, {"06:30-07:45", 4}, {"07:45-09:45", 3}, {"09:45-11:00", 2}, {"11:00-12:00", 1}}},
+	{"If you were entirely free to plan your evening, what time would you go to bed?", []Option{
+		{"20:00-21:00", 5}, {"21:00-22:15", 4}, {"22:15-00:30", 3}, {"00:30-01:45", 2}, {"01:45-03:00", 1}}},
+	{"If you have to get up at a specific time, how much do you depend on an alarm clock?", []Option{
+		{"Not at all", 4}, {"Slightly", 3}, {"Somewhat", 2}, {"Very much", 1}}},
+	{"How easy do you find it to get up in the morning (when not woken unexpectedly)?", []Option{
+		{"Very easy", 4}, {"Fairly easy", 3}, {"Not very easy", 2}, {"Not at all easy", 1}}},
+	{"How alert do you feel during the first half hour after you wake up?", []Option{
+		{"Very alert", 4}, {"Fairly alert", 3}, {"Slightly alert", 2}, {"Not at all alert", 1}}},
+	{"How hungry do you feel during the first half hour after you wake up?", []Option{
+		{"Very hungry", 4}, {"Fairly hungry", 3}, {"Slightly hungry", 2}, {"Not at all hungry", 1}}},
+	{"During the first half hour after you wake up, how do you feel?", []Option{
+		{"Very refreshed", 4}, {"Fairly refreshed", 3}, {"Fairly tired", 2}, {"Very tired", 1}}},
+	{"If you had no commitments the next day, what time would you go to bed compared to your usual bedtime?", []Option{
+		{"Seldom or never later", 4}, {"Less than 1 hour later", 3}, {"1-2 hours later", 2}, {"More than 2 hours later", 1}}},
+	{"A friend suggests exercising 07:00-08:00 twice a week. How would you perform?", []Option{
+		{"Would be in good form", 4}, {"Would be in reasonable form", 3}, {"Would find it difficult", 2}, {"Would find it very difficult", 1}}},
+	{"At what time in the evening do you feel tired and in need of sleep?", []Option{
+		{"20:00-21:00", 5}, {"21:00-22:15", 4}, {"22:15-00:45", 3}, {"00:45-02:00", 2}, {"02:00-03:00", 1}}},
+	{"You must be at peak performance for a two-hour, mentally exhausting test. Which slot would you choose?", []Option{
+		{"08:00-10:00", 6}, {"11:00-13:00", 4}, {"15:00-17:00", 2}, {"19:00-21:00", 0}}},
+	{"If you got into bed at 23:00, how tired would you be?", []Option{
+		{"Not at all tired", 0}, {"A little tired", 2}, {"Fairly tired", 3}, {"Very tired", 5}}},
+	{"You went to bed several hours later than usual, with no need to get up early. What happens?", []Option{
+		{"Wake at the usual time and stay up", 4}, {"Wake at the usual time, then doze", 3}, {"Wake at the usual time, then fall asleep again", 2}, {"Wake later than usual", 1}}},
+	{"You have to stay awake 04:00-06:00 for a night watch, with no commitments the next day. What would you do?", []Option{
+		{"Not go to bed until it is over", 1}, {"Nap before and sleep after", 2}, {"Sleep before and nap after", 3}, {"Sleep only before", 4}}},
+	{"You have two hours of hard physical work. Which slot would you choose?", []Option{
+		{"08:00-10:00", 4}, {"11:00-13:00", 3}, {"15:00-17:00", 2}, {"19:00-21:00", 1}}},
+	{"A friend suggests exercising 22:00-23:00 twice a week. How would you perform?", []Option{
+		{"Would be in good form", 1}, {"Would be in reasonable form", 2}, {"Would find it difficult", 3}, {"Would find it very difficult", 4}}},
+	{"You choose your own five-hour work block. When would it start?", []Option{
+		{"04:00-08:00", 5}, {"08:00-09:00", 4}, {"09:00-14:00", 3}, {"14:00-17:00", 2}, {"17:00-04:00", 1}}},
+	{"At what time of day do you feel your best?", []Option{
+		{"05:00-08:00", 5}, {"08:00-10:00", 4}, {"10:00-17:00", 3}, {"17:00-22:00", 2}, {"22:00-05:00", 1}}},
+	{"People are 'morning' or 'evening' types. Which are you?", []Option{
+		{"Definitely a morning type", 6}, {"Rather more a morning type", 4}, {"Rather more an evening type", 2}, {"Definitely an evening type", 0}}},
+}
+
+// MEQ score bounds and the neutral midpoint used for mapping.
+const (
+	meqMin     = 16
+	meqMax     = 86
+	meqNeutral = 50
+)
+
+// ScoreMEQ totals the questionnaire. Each answer is the index of the chosen option for that item.
+func ScoreMEQ(answers []int) (int, error) {
+	if len(answers) != len(MEQItems) {
+		return 0, fmt.Errorf("expected %d MEQ answers, got %d", len(MEQItems), len(answers))
+	}
+
+	total := 0
+	for i, choice := range answers {
+		options := MEQItems[i].Options
+		if choice < 0 || choice >= len(options) {
+			return 0, fmt.Errorf("MEQ item %d: option %d out of range 0-%d", i+1, choice, len(options)-1)
+		}
+		total += options[choice].Score
+	}
+	return total, nil
+}
+
+// MEQCategory returns the standard Horne & Östberg label for a total score.
+func MEQCategory(score int) string {
+	switch {
+	case score >= 70:
+		return "Definite Morning"
+	case score >= 59:
+		return "Moderate Morning"
+	case score >= 42:
+		return "Neither"
+	case score >= 31:
+		return "Moderate Evening"
+	default:
+		return "Definite Evening"
+	}
+}
+
+// FromMEQ maps an MEQ total onto model parameters.
+// Every 10 points away from neutral shifts the circadian curve by one hour (evening types later),
+// and morning types get a slightly faster build-up of sleep pressure, as reported in the literature.
+func FromMEQ(score int) (Result, error) {
+	if score < meqMin || score > meqMax {
+		return Result{}, fmt.Errorf("MEQ score %d outside %d-%d", score, meqMin, meqMax)
+	}
+
+	offset := float64(meqNeutral - score) // Positive = evening
+	return Result{
+		Method:        "MEQ",
+		Score:         float64(score),
+		Category:      MEQCategory(score),
+		ChronotypeLag: clamp(offset/10.0, -maxLag, maxLag),
+		FatigueRate:   clamp(defaultFatigueRate+offset*0.05, minFatigueRate, maxFatigueRate),
+	}, nil
+}
+
+// clamp restricts v to [lo, hi].
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sitanshunandan/tardigo/internal/biomodel"
+)
+
+// SaveAlertnessReport records a Karolinska Sleepiness Scale self-report.
+func (r *TelemetryRepository) SaveAlertnessReport(ctx context.Context, userID string, timestamp time.Time, kss int) error {
+	query := `
+		INSERT INTO alertness_reports (time, user_id, kss)
+		VALUES ($1, $2, $3)
+	`
+	_, err := r.conn.Exec(ctx, query, timestamp, userID, kss)
+	return err
+}
+
+// SaveHeartMetrics inserts a wearable reading into bio_telemetry.
+// The model columns stay NULL: this row is an observation, not a prediction.
+func (r *TelemetryRepository) SaveHeartMetrics(ctx context.Context, userID string, timestamp time.Time, heartRate, hrv float64) error {
+	query := `
+		INSERT INTO bio_telemetry (time, user_id, heart_rate, hrv)
+		VALUES ($1, $2, $3, $4)
+	`
+	_, err := r.conn.Exec(ctx, query, timestamp, userID, heartRate, hrv)
+	return err
+}
+
+// GetObservations collects everything we can fit the model to since a point in time:
+// self-reported alertness plus HRV readings.
+func (r *TelemetryRepository) GetObservations(ctx context.Context, userID string, since time.Time) ([]biomodel.Observation, error) {
+	var obs []biomodel.Observation
+
+	// 1. Self-reports
+	rows, err := r.conn.Query(ctx, `
+		SELECT time, kss
+		FROM alertness_reports
+		WHERE user_id = $1 AND time > $2
+		ORDER BY time ASC
+	`, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alertness reports: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t time.Time
+		var kss int
+		if err := rows.Scan(&t, &kss); err != nil {
+			return nil, fmt.Errorf("failed to scan alertness report: %w", err)
+		}
+		o, err := biomodel.ObservationFromKSS(t, kss)
+		if err != nil {
+			return nil, err
+		}
+		obs = append(obs, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 2. HRV
+	hrvRows, err := r.conn.Query(ctx, `
+		SELECT time, hrv
+		FROM bio_telemetry
+		WHERE user_id = $1 AND time > $2 AND hrv IS NOT NULL
+		ORDER BY time ASC
+	`, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query HRV: %w", err)
+	}
+	defer hrvRows.Close()
+
+	var samples []biomodel.HRVSample
+	for hrvRows.Next() {
+		var s biomodel.HRVSample
+		if err := hrvRows.Scan(&s.Time, &s.HRV); err != nil {
+			return nil, fmt.Errorf("failed to scan HRV: %w", err)
+		}
+		samples = append(samples, s)
+	}
+	if err := hrvRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return append(obs, biomodel.ObservationsFromHRV(samples)...), nil
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sitanshunandan/tardigo/internal/biomodel"
+)
+
+// UserProfile holds the per-user settings of the bio-model.
+type UserProfile struct {
+	UserID        string    `json:"user_id"`
+	ChronotypeLag float64   `json:"chronotype_lag"`
+	FatigueRate   float64   `json:"fatigue_rate"`
+	Chronotype    string    `json:"chronotype"`
+	TimeZone      string    `json:"time_zone"` // IANA zone, e.g. "Europe/Berlin". Empty means the server's zone.
+	UpdatedAt     time.Time `json:"updated_at"`
+
+	// Set once FitParams has run for this user (see SaveFit)
+	Weights *biomodel.Weights   `json:"weights,omitempty"`
+	Fit     *biomodel.FitResult `json:"fit,omitempty"`
+
+	// Set once FitCircadianProfile has run (see SaveCircadianProfile). Nil means the default sinusoid.
+	Circadian *biomodel.CircadianProfile `json:"circadian,omitempty"`
+}
+
+// SaveProfile creates or replaces a user's calibrated settings. Any earlier fit (weights and fit
+// quality) is cleared: it was fitted together with the lag and fatigue rate being replaced, so it
+// no longer describes them. So is a fitted circadian profile if the lag changes (see
+// clearCircadianOnNewLag). The stored time zone is kept unless p carries a new one.
+func (r *TelemetryRepository) SaveProfile(ctx context.Context, p UserProfile) error {
+	query := `
+		INSERT INTO user_profiles (user_id, chronotype_lag, fatigue_rate, chronotype, time_zone, updated_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NOW())
+		ON CONFLICT (user_id)
+		DO UPDATE SET chronotype_lag = EXCLUDED.chronotype_lag,
+		              fatigue_rate = EXCLUDED.fatigue_rate,
+		              chronotype = EXCLUDED.chronotype,
+		              time_zone = COALESCE(EXCLUDED.time_zone, user_profiles.time_zone),
+		              weight_homeostatic = NULL,
+		              weight_circadian = NULL,
+		              weight_inertia = NULL,
+		              fit_rmse = NULL,
+		              fit_r_squared = NULL,
+		              fit_samples = NULL,
+		              fitted_at = NULL,
+		              ` + clearCircadianOnNewLag + `,
+		              updated_at = EXCLUDED.updated_at
+	`
+	_, err := r.conn.Exec(ctx, query, p.UserID, p.ChronotypeLag, p.FatigueRate, p.Chronotype, p.TimeZone)
+	return err
+}
+
+// GetProfile fetches a user's profile. It returns nil (and no error) if the user never calibrated.
+func (r *TelemetryRepository) GetProfile(ctx context.Context, userID string) (*UserProfile, error) {
+	query := `
+		SELECT user_id, chronotype_lag, fatigue_rate, COALESCE(chronotype, ''), COALESCE(time_zone, ''), updated_at,
+		       weight_homeostatic, weight_circadian, weight_inertia,
+		       fit_rmse, fit_r_squared, fit_samples, circadian_profile
+		FROM user_profiles
+		WHERE user_id = $1
+	`
+
+	var p UserProfile
+	var wS, wC, wW, rmse, r2 *float64
+	var samples *int
+	var circadian []byte
+	err := r.conn.QueryRow(ctx, query, userID).Scan(
+		&p.UserID,
+		&p.ChronotypeLag,
+		&p.FatigueRate,
+		&p.Chronotype,
+		&p.TimeZone,
+		&p.UpdatedAt,
+		&wS, &wC, &wW,
+		&rmse, &r2, &samples,
+		&circadian,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load profile for %s: %w", userID, err)
+	}
+
+	if wS != nil && wC != nil && wW != nil {
+		p.Weights = &biomodel.Weights{Homeostatic: *wS, Circadian: *wC, Inertia: *wW}
+	}
+	if p.Weights != nil && rmse != nil && r2 != nil && samples != nil {
+		p.Fit = &biomodel.FitResult{
+			FatigueRate:   p.FatigueRate,
+			ChronotypeLag: p.ChronotypeLag,
+			Weights:       *p.Weights,
+			RMSE:          *rmse,
+			RSquared:      *r2,
+			Samples:       *samples,
+		}
+	}
+
+	if circadian != nil {
+		var profile biomodel.CircadianProfile
+		if err := json.Unmarshal(circadian, &profile); err != nil {
+			return nil, fmt.Errorf("failed to decode circadian profile for %s: %w", userID, err)
+		}
+		p.Circadian = &profile
+	}
+
+	return &p, nil
+}
+
+// clearCircadianOnNewLag drops the stored circadian profile when an upsert changes the lag.
+// FitCircadianProfile fits its harmonics relative to the lag, so under a new one the fitted dip
+// would move by the difference. The handler that refits both saves the new profile afterwards.
+const clearCircadianOnNewLag = `circadian_profile = CASE
+		                  WHEN user_profiles.chronotype_lag IS DISTINCT FROM EXCLUDED.chronotype_lag THEN NULL
+		                  ELSE user_profiles.circadian_profile
+		              END`
+
+// SaveFit stores the outcome of parameter fitting: it overrides the calibrated lag and fatigue
+// rate and records the weights plus fit quality. A circadian profile fitted under another lag is
+// cleared.
+func (r *TelemetryRepository) SaveFit(ctx context.Context, userID string, fit biomodel.FitResult) error {
+	query := `
+		INSERT INTO user_profiles (user_id, chronotype_lag, fatigue_rate,
+		                           weight_homeostatic, weight_circadian, weight_inertia,
+		                           fit_rmse, fit_r_squared, fit_samples, fitted_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+		ON CONFLICT (user_id)
+		DO UPDATE SET chronotype_lag = EXCLUDED.chronotype_lag,
+		              fatigue_rate = EXCLUDED.fatigue_rate,
+		              weight_homeostatic = EXCLUDED.weight_homeostatic,
+		              weight_circadian = EXCLUDED.weight_circadian,
+		              weight_inertia = EXCLUDED.weight_inertia,
+		              fit_rmse = EXCLUDED.fit_rmse,
+		              fit_r_squared = EXCLUDED.fit_r_squared,
+		              fit_samples = EXCLUDED.fit_samples,
+		              fitted_at = EXCLUDED.fitted_at,
+		              ` + clearCircadianOnNewLag + `,
+		              updated_at = EXCLUDED.updated_at
+	`
+	_, err := r.conn.Exec(ctx, query, userID, fit.ChronotypeLag, fit.FatigueRate,
+		fit.Weights.Homeostatic, fit.Weights.Circadian, fit.Weights.Inertia,
+		fit.RMSE, fit.RSquared, fit.Samples)
+	return err
+}
+
+// SaveCircadianProfile stores a fitted (or hand-entered) shape for the user's Process C.
+func (r *TelemetryRepository) SaveCircadianProfile(ctx context.Context, userID string, profile biomodel.CircadianProfile) error {
+	encoded, err := json.Marshal(profile)
+	if err != nil {
+		return fmt.Errorf("failed to encode circadian profile: %w", err)
+	}
+
+	query := `
+		INSERT INTO user_profiles (user_id, circadian_profile, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id)
+		DO UPDATE SET circadian_profile = EXCLUDED.circadian_profile,
+		              updated_at = EXCLUDED.updated_at
+	`
+	_, err = r.conn.Exec(ctx, query, userID, string(encoded))
+	return err
+}
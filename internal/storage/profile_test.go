@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/sitanshunandan/tardigo/internal/biomodel"
+)
+
+// testRepository connects to the database named by TARDIGO_TEST_DB_URL, which must already carry
+// the migrations (docker compose up gives one). Without it the test is skipped.
+func testRepository(t *testing.T) *TelemetryRepository {
+	t.Helper()
+	url := os.Getenv("TARDIGO_TEST_DB_URL")
+	if url == "" {
+		t.Skip("TARDIGO_TEST_DB_URL not set")
+	}
+	repo, err := NewTelemetryRepository(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close(context.Background()) })
+	return repo
+}
+
+func TestCircadianProfileClearedWhenLagChanges(t *testing.T) {
+	ctx := context.Background()
+	repo := testRepository(t)
+	userID := "test-" + t.Name()
+	t.Cleanup(func() {
+		repo.conn.Exec(context.Background(), `DELETE FROM user_profiles WHERE user_id = $1`, userID)
+	})
+
+	fitted := biomodel.CircadianProfile{Harmonics: []biomodel.Harmonic{{Period: 24, Amplitude: 1, Peak: 16}}}
+	refit := func(t *testing.T) {
+		t.Helper()
+		if err := repo.SaveProfile(ctx, UserProfile{UserID: userID, ChronotypeLag: 1, FatigueRate: 0.05}); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.SaveCircadianProfile(ctx, userID, fitted); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		save func() error
+		kept bool
+	}{
+		{"profile, same lag", func() error {
+			return repo.SaveProfile(ctx, UserProfile{UserID: userID, ChronotypeLag: 1, FatigueRate: 0.06})
+		}, true},
+		{"profile, new lag", func() error {
+			return repo.SaveProfile(ctx, UserProfile{UserID: userID, ChronotypeLag: 2, FatigueRate: 0.05})
+		}, false},
+		{"fit, same lag", func() error {
+			return repo.SaveFit(ctx, userID, biomodel.FitResult{ChronotypeLag: 1, FatigueRate: 0.05, Weights: biomodel.DefaultWeights, Samples: 20})
+		}, true},
+		{"fit, new lag", func() error {
+			return repo.SaveFit(ctx, userID, biomodel.FitResult{ChronotypeLag: -1, FatigueRate: 0.05, Weights: biomodel.DefaultWeights, Samples: 20})
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refit(t)
+			if err := tt.save(); err != nil {
+				t.Fatal(err)
+			}
+			p, err := repo.GetProfile(ctx, userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kept := p.Circadian != nil; kept != tt.kept {
+				t.Errorf("circadian profile kept = %v, want %v", kept, tt.kept)
+			}
+		})
+	}
+}
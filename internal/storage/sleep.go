@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sitanshunandan/tardigo/internal/biomodel"
+)
+
+// SaveSleepEpisode records (or updates) a sleep episode for a user.
+// Episodes are keyed by their start time, so re-logging the same night overwrites it.
+func (r *TelemetryRepository) SaveSleepEpisode(ctx context.Context, userID string, ep biomodel.SleepEpisode) error {
+	query := `
+		INSERT INTO sleep_log (user_id, start_time, end_time, planned, nap)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, start_time)
+		DO UPDATE SET end_time = EXCLUDED.end_time, planned = EXCLUDED.planned, nap = EXCLUDED.nap
+	`
+	_, err := r.conn.Exec(ctx, query, userID, ep.Start, ep.End, ep.Planned, ep.Nap)
+	return err
+}
+
+// GetSleepLog fetches every episode for a user that ended after 'since', oldest first.
+func (r *TelemetryRepository) GetSleepLog(ctx context.Context, userID string, since time.Time) (biomodel.SleepLog, error) {
+	query := `
+		SELECT start_time, end_time, planned, nap
+		FROM sleep_log
+		WHERE user_id = $1 AND end_time > $2
+		ORDER BY start_time ASC
+	`
+
+	rows, err := r.conn.Query(ctx, query, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sleep log: %w", err)
+	}
+	defer rows.Close()
+
+	var log biomodel.SleepLog
+	for rows.Next() {
+		var ep biomodel.SleepEpisode
+		if err := rows.Scan(&ep.Start, &ep.End, &ep.Planned, &ep.Nap); err != nil {
+			return nil, fmt.Errorf("failed to scan sleep episode: %w", err)
+		}
+		log = append(log, ep)
+	}
+
+	return log, rows.Err()
+}
@@ -57,7 +57,7 @@ func (r *TelemetryRepository) GetLatestCapacity(ctx context.Context, userID stri
 	query := `
 		SELECT process_s, process_c, overall_capacity 
 		FROM bio_telemetry 
-		WHERE user_id = $1 
+		WHERE user_id = $1 AND overall_capacity IS NOT NULL
 		ORDER BY time DESC 
 		LIMIT 1
 	`
@@ -0,0 +1,12 @@
+-- 1. Sleep episodes per user (observed and planned)
+-- The bio-model integrates Process S across these: pressure decays while asleep, rises while awake.
+CREATE TABLE IF NOT EXISTS sleep_log (
+    user_id     TEXT NOT NULL,
+    start_time  TIMESTAMPTZ NOT NULL, -- Lights out
+    end_time    TIMESTAMPTZ NOT NULL, -- Wake up
+    planned     BOOLEAN NOT NULL DEFAULT FALSE, -- Future night we expect, not one we observed
+    PRIMARY KEY (user_id, start_time)
+);
+
+-- 2. Index for "everything since X" lookups
+CREATE INDEX IF NOT EXISTS idx_sleep_log_user_end ON sleep_log (user_id, end_time DESC);
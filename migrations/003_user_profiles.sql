@@ -0,0 +1,9 @@
+-- 1. Per-user bio-model settings
+-- Written by calibration (questionnaires) and read by every scheduler call.
+CREATE TABLE IF NOT EXISTS user_profiles (
+    user_id         TEXT PRIMARY KEY,
+    chronotype_lag  DOUBLE PRECISION NOT NULL DEFAULT 0.0,  -- Hours; + = Night Owl
+    fatigue_rate    DOUBLE PRECISION NOT NULL DEFAULT 16.0, -- Process S rise time constant (hours)
+    chronotype      TEXT,                                   -- e.g. "Moderate Evening"
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
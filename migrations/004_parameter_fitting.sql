@@ -0,0 +1,20 @@
+-- 1. Self-reported alertness on the Karolinska Sleepiness Scale (1 = extremely alert, 9 = fighting sleep)
+CREATE TABLE IF NOT EXISTS alertness_reports (
+    time        TIMESTAMPTZ NOT NULL,
+    user_id     TEXT NOT NULL,
+    kss         SMALLINT NOT NULL CHECK (kss BETWEEN 1 AND 9)
+);
+
+SELECT create_hypertable('alertness_reports', 'time', if_not_exists => TRUE);
+CREATE INDEX IF NOT EXISTS idx_alertness_reports_user ON alertness_reports (user_id, time DESC);
+
+-- 2. Fitted parameters live next to the calibrated ones.
+-- NULL weights mean the user has never been fitted, so the scheduler uses the default model.
+ALTER TABLE user_profiles
+    ADD COLUMN IF NOT EXISTS weight_homeostatic DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS weight_circadian   DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS weight_inertia     DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS fit_rmse           DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS fit_r_squared      DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS fit_samples        INTEGER,
+    ADD COLUMN IF NOT EXISTS fitted_at          TIMESTAMPTZ;
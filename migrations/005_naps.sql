@@ -0,0 +1,4 @@
+-- 1. Naps live in the sleep log next to nights.
+-- They dissipate sleep pressure like any sleep but leave less inertia, and don't replace the night.
+ALTER TABLE sleep_log
+    ADD COLUMN IF NOT EXISTS nap BOOLEAN NOT NULL DEFAULT FALSE;
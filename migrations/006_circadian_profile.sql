@@ -0,0 +1,4 @@
+-- 1. Per-user shape of Process C (harmonics or a sampled curve), stored as JSON.
+-- NULL keeps the classic sinusoid.
+ALTER TABLE user_profiles
+    ADD COLUMN IF NOT EXISTS circadian_profile JSONB;
@@ -0,0 +1,4 @@
+-- 1. IANA zone the user lives in (e.g. 'Europe/Berlin'). Circadian phase and returned times use it.
+-- NULL keeps the server's zone.
+ALTER TABLE user_profiles
+    ADD COLUMN IF NOT EXISTS time_zone TEXT;